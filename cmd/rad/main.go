@@ -13,15 +13,24 @@ import (
 	"time"
 
 	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/auth"
 	"github.com/reviewapps-dev/rad/internal/buildqueue"
 	"github.com/reviewapps-dev/rad/internal/caddy"
+	"github.com/reviewapps-dev/rad/internal/certs"
 	"github.com/reviewapps-dev/rad/internal/config"
 	"github.com/reviewapps-dev/rad/internal/deploy"
+	"github.com/reviewapps-dev/rad/internal/diskusage"
+	"github.com/reviewapps-dev/rad/internal/execjob"
 	"github.com/reviewapps-dev/rad/internal/heartbeat"
 	"github.com/reviewapps-dev/rad/internal/logstream"
+	"github.com/reviewapps-dev/rad/internal/logwriter"
 	"github.com/reviewapps-dev/rad/internal/monitor"
 	"github.com/reviewapps-dev/rad/internal/port"
+	"github.com/reviewapps-dev/rad/internal/reaper"
 	"github.com/reviewapps-dev/rad/internal/server"
+	"github.com/reviewapps-dev/rad/internal/sleeper"
+	"github.com/reviewapps-dev/rad/internal/supervisor"
+	"github.com/reviewapps-dev/rad/internal/teardown"
 	"github.com/reviewapps-dev/rad/internal/updater"
 	"github.com/reviewapps-dev/rad/internal/version"
 )
@@ -36,6 +45,11 @@ func main() {
 		case "version":
 			fmt.Printf("rad %s (%s) built %s\n", version.Version, version.Commit, version.BuildDate)
 			return
+		case logwriter.RelayCommand:
+			if err := logwriter.RunRelay(os.Args[2:]); err != nil {
+				log.Fatalf("log-writer: %v", err)
+			}
+			return
 		}
 	}
 
@@ -70,7 +84,9 @@ func main() {
 	}
 
 	// In dev mode, auto-generate a stream token if not provided
-	if cfg.Dev && cfg.Auth.StreamToken == "" {
+	if cfg.Auth.DisableStreamToken {
+		cfg.Auth.StreamToken = ""
+	} else if cfg.Dev && cfg.Auth.StreamToken == "" {
 		cfg.Auth.StreamToken = "stream-" + cfg.Auth.Token
 		log.Printf("dev: auto-generated stream token: %s", cfg.Auth.StreamToken)
 	}
@@ -104,11 +120,24 @@ func main() {
 		log.Printf("caddy: disabled (dev mode — apps accessible via localhost:{port})")
 	}
 
-	// Create the log streaming hub
+	// Create the log streaming hub, and the lifecycle event hub behind
+	// type=all log streams
 	hub := logstream.NewHub()
+	events := logstream.NewEvents()
+	store.OnStatusChange(func(appID string, status app.Status, errMsg string) {
+		msg := string(status)
+		if errMsg != "" {
+			msg += ": " + errMsg
+		}
+		events.Publish(logstream.Event{AppID: appID, Type: logstream.EventStatus, Message: msg})
+	})
+
+	// Process supervisor shared by the API, the crash monitor and rollbacks
+	sup := supervisor.New(cfg, store, events)
 
 	// Build the deploy pipeline
-	pipeline := deploy.NewPipeline(cfg, store, ports, cm, hub)
+	pipeline := deploy.NewPipeline(cfg, store, ports, cm, hub, events, sup)
+	pipeline.AddStep(&deploy.AdmissionStep{})
 	pipeline.AddStep(&deploy.CreateDirStep{})
 	pipeline.AddStep(&deploy.GitCloneStep{})
 	pipeline.AddStep(&deploy.DetectConfigStep{})
@@ -133,14 +162,47 @@ func main() {
 	pipeline.AddStep(&deploy.AllocatePortStep{})
 	pipeline.AddStep(&deploy.StartProcessesStep{})
 	pipeline.AddStep(&deploy.HealthCheckStep{})
+	pipeline.AddStep(&deploy.ActivateReleaseStep{})
 	pipeline.AddStep(&deploy.CaddyConfigStep{})
+	pipeline.AddStep(&deploy.CutoverStep{})
 	pipeline.AddStep(&deploy.RunHooksStep{Phase: deploy.HookAfterDeploy})
 	pipeline.AddStep(&deploy.CallbackStep{})
 
-	srv := server.New(cfg, store, ports, queue, cm, hub)
+	// Idle sleep and wake-on-request
+	sl := sleeper.New(cfg, store, sup, cm, time.Minute)
+
+	// Background exec jobs, killed when their app is torn down
+	jobs := execjob.NewManager(cfg.Exec.MaxJobsPerApp, cfg.Exec.MaxJobs)
+
+	// Teardown shared by DELETE /apps/{id} and the expiry reaper
+	td := teardown.New(cfg, store, ports, cm, sup, queue, hub, jobs)
+
+	// API tokens: the main token plus scoped tokens from config
+	tokens, err := auth.NewStore(cfg)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	// HTTPS for the API, with certificates reloaded when they change or on SIGHUP
+	// Misconfigured TLS is fatal rather than falling back to an open API.
+	var tlsCerts *certs.Reloader
+	if cfg.Server.TLS {
+		if cfg.Server.CertFile == "" || cfg.Server.KeyFile == "" {
+			log.Fatalf("tls: enabled but cert_file and key_file are not both set")
+		}
+		tlsCerts, err = certs.New(cfg.Server.CertFile, cfg.Server.KeyFile, cfg.Server.ClientCAFile, 30*time.Second)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+	} else if cfg.Server.ClientCAFile != "" {
+		log.Fatalf("tls: client_ca_file is set but tls is off")
+	}
+
+	srv := server.New(cfg, store, ports, queue, cm, hub, events, sup, sl, td, jobs, tokens, tlsCerts)
 	srv.SetDeployFunc(func(ctx context.Context, state *app.AppState, redeploy bool) error {
 		return pipeline.Run(ctx, state, redeploy)
 	})
+	srv.SetRollbackFunc(pipeline.Rollback)
 
 	// Graceful shutdown
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
@@ -148,14 +210,41 @@ func main() {
 
 	queue.Start(ctx)
 
+	if tlsCerts != nil {
+		tlsCerts.Start()
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				log.Printf("tls: reloading on SIGHUP")
+				if err := tlsCerts.Reload(); err != nil {
+					log.Printf("tls: %v (keeping previous certificate)", err)
+				}
+			}
+		}()
+	}
+
 	// Start heartbeat
 	hb := heartbeat.New(cfg, store)
 	hb.Start(30 * time.Second)
 
 	// Start process crash monitor
-	mon := monitor.New(cfg, store, 15*time.Second)
+	mon := monitor.New(cfg, store, sup, 15*time.Second)
 	mon.Start()
 
+	sl.Start()
+
+	// Tear down apps past their expires_at
+	rp := reaper.New(store, td, time.Minute)
+	rp.Start()
+
+	// Prune tmp/cache, stale assets and releases, and orphaned logs
+	var cleaner *diskusage.Cleaner
+	if cfg.Cleanup.IntervalMinutes > 0 {
+		cleaner = diskusage.NewCleaner(cfg, store, time.Duration(cfg.Cleanup.IntervalMinutes)*time.Minute)
+		cleaner.Start()
+	}
+
 	go func() {
 		if err := srv.Start(); err != nil && err.Error() != "http: Server closed" {
 			log.Fatalf("server: %v", err)
@@ -177,6 +266,14 @@ func main() {
 	queue.Stop()
 	hb.Stop()
 	mon.Stop()
+	sl.Stop()
+	rp.Stop()
+	if tlsCerts != nil {
+		tlsCerts.Stop()
+	}
+	if cleaner != nil {
+		cleaner.Stop()
+	}
 	log.Println("rad stopped")
 }
 
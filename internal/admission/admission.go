@@ -0,0 +1,134 @@
+package admission
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/config"
+)
+
+// Error is returned when a deploy would push the host past a configured
+// limit. Status is the HTTP status the deploy should be rejected with.
+type Error struct {
+	Status int
+	Reason string
+	// Evictable is true if tearing down another app would free what's
+	// missing (disk or an app slot, but not memory).
+	Evictable bool
+}
+
+func (e *Error) Error() string { return e.Reason }
+
+// Checker decides whether the host has room for another deploy, based on the
+// [limits] section of config.toml. Zero limits are not checked.
+type Checker struct {
+	cfg   *config.Config
+	store *app.Store
+}
+
+func New(cfg *config.Config, store *app.Store) *Checker {
+	return &Checker{
+		cfg:   cfg,
+		store: store,
+	}
+}
+
+// Check returns an *Error if deploying appID would exceed a limit. A redeploy
+// of an existing app doesn't count against max_apps.
+func (c *Checker) Check(appID string) error {
+	limits := c.cfg.Limits
+
+	if limits.MaxApps > 0 {
+		count := 0
+		for _, state := range c.store.List() {
+			if state.AppID != appID {
+				count++
+			}
+		}
+		if count >= limits.MaxApps {
+			return &Error{
+				Status:    http.StatusServiceUnavailable,
+				Reason:    fmt.Sprintf("server is at its limit of %d apps", limits.MaxApps),
+				Evictable: true,
+			}
+		}
+	}
+
+	if limits.MinFreeDiskMB > 0 {
+		free, err := FreeDiskMB(c.cfg.Paths.AppsDir)
+		if err == nil && free < limits.MinFreeDiskMB {
+			return &Error{
+				Status:    http.StatusInsufficientStorage,
+				Reason:    fmt.Sprintf("only %d MB free under %s (need %d MB)", free, c.cfg.Paths.AppsDir, limits.MinFreeDiskMB),
+				Evictable: true,
+			}
+		}
+	}
+
+	if limits.MinFreeMemoryMB > 0 {
+		avail, err := AvailableMemoryMB()
+		if err == nil && avail < limits.MinFreeMemoryMB {
+			return &Error{
+				Status: http.StatusServiceUnavailable,
+				Reason: fmt.Sprintf("only %d MB memory available (need %d MB)", avail, limits.MinFreeMemoryMB),
+			}
+		}
+	}
+
+	return nil
+}
+
+// LeastRecentlyUsedSleeping returns the app that has been sleeping the
+// longest, other than appID and among those allowed accepts, or nil if
+// there is none.
+func (c *Checker) LeastRecentlyUsedSleeping(appID string, allowed func(appID string) bool) *app.AppState {
+	var lru *app.AppState
+	for _, state := range c.store.List() {
+		if state.Status != app.StatusSleeping || state.AppID == appID || !allowed(state.AppID) {
+			continue
+		}
+		if lru == nil || state.UpdatedAt.Before(lru.UpdatedAt) {
+			lru = state
+		}
+	}
+	return lru
+}
+
+// FreeDiskMB returns the space available to unprivileged users on the
+// filesystem holding path.
+func FreeDiskMB(path string) (int, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return int(st.Bavail * uint64(st.Bsize) / 1024 / 1024), nil
+}
+
+// AvailableMemoryMB returns MemAvailable from /proc/meminfo. Errors on
+// systems without it (macOS in dev mode), where the check is skipped.
+func AvailableMemoryMB() (int, error) {
+	f, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return 0, err
+			}
+			return kb / 1024, nil
+		}
+	}
+	return 0, fmt.Errorf("MemAvailable not found in /proc/meminfo")
+}
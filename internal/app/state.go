@@ -1,6 +1,11 @@
 package app
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,6 +19,7 @@ const (
 	StatusRunning   Status = "running"
 	StatusFailed    Status = "failed"
 	StatusStopped   Status = "stopped"
+	StatusSleeping  Status = "sleeping"
 	StatusTeardown  Status = "teardown"
 )
 
@@ -28,9 +34,34 @@ type Hooks struct {
 }
 
 type ProcessInfo struct {
-	Name string `json:"name"`
-	PID  int    `json:"pid"`
-	Port int    `json:"port,omitempty"` // Only the web process gets a port
+	Name      string    `json:"name"`
+	PID       int       `json:"pid"`            // 0 when the process has been stopped
+	Port      int       `json:"port,omitempty"` // Only the web process gets a port
+	StartedAt time.Time `json:"started_at,omitempty"`
+	Restarts  int       `json:"restarts,omitempty"` // Restarts since the last deploy
+}
+
+// Release records a successful deploy so the app can be rolled back to it.
+type Release struct {
+	ID              string            `json:"id,omitempty"` // Directory name under releases/
+	CommitSHA       string            `json:"commit_sha"`
+	ProcessCommands map[string]string `json:"process_commands,omitempty"`
+	DeployedAt      time.Time         `json:"deployed_at"`
+}
+
+// Build kinds.
+const (
+	BuildDeploy   = "deploy"
+	BuildRedeploy = "redeploy"
+	BuildRollback = "rollback"
+	BuildTeardown = "teardown"
+)
+
+// Build references the on-disk log of one deploy or teardown.
+type Build struct {
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	StartedAt time.Time `json:"started_at"`
 }
 
 type AppState struct {
@@ -56,9 +87,130 @@ type AppState struct {
 	PID            int                    `json:"pid,omitempty"`            // Primary (web) process PID for backward compat
 	Processes      map[string]ProcessInfo `json:"processes,omitempty"`      // All managed processes
 	ProcessCommands map[string]string     `json:"process_commands,omitempty"` // Process name → command for restart
+	Scale          map[string]int         `json:"scale,omitempty"`           // Instance count per process type from reviewapps.yml
+	ScaleOverrides map[string]int         `json:"scale_overrides,omitempty"` // Instance counts set at runtime via POST /apps/{id}/scale
 	AppDir         string                 `json:"app_dir,omitempty"`
+	AppPath        string                 `json:"app_path,omitempty"` // Monorepo subdirectory from reviewapps.yml
+	Releases       []Release              `json:"releases,omitempty"` // Successful deploys, newest first
+	ExpiresAt      time.Time              `json:"expires_at,omitempty"` // Torn down by the reaper after this; zero means never
+	TeardownDone   []string               `json:"teardown_done,omitempty"` // Teardown steps already completed, skipped on retry
+	TeardownFailures int                  `json:"teardown_failures,omitempty"`   // Failed teardown attempts
+	TeardownFailedAt time.Time            `json:"teardown_failed_at,omitempty"` // When the last teardown attempt failed
 	Error          string                 `json:"error,omitempty"`
 	CreatedAt      time.Time              `json:"created_at"`
 	UpdatedAt      time.Time              `json:"updated_at"`
-	BuildLog       []string               `json:"build_log,omitempty"`
+	Builds         []Build                `json:"builds,omitempty"` // Build logs on disk, newest first
+}
+
+// ScaleFor returns how many instances of a process type should run.
+// Runtime overrides win over reviewapps.yml; the default is one.
+func (s *AppState) ScaleFor(procType string) int {
+	if n, ok := s.ScaleOverrides[procType]; ok {
+		return n
+	}
+	if n, ok := s.Scale[procType]; ok {
+		return n
+	}
+	return 1
+}
+
+// InstanceNames returns the process names for count instances of a process type.
+// A single instance keeps the plain type name (worker); more than one are
+// numbered worker.1, worker.2, ... so each gets its own log file and PID.
+func InstanceNames(procType string, count int) []string {
+	if count == 1 {
+		return []string{procType}
+	}
+	names := make([]string, 0, count)
+	for i := 1; i <= count; i++ {
+		names = append(names, fmt.Sprintf("%s.%d", procType, i))
+	}
+	return names
+}
+
+// ValidateID checks an app ID. Log files are named {app_id}.{process}.log,
+// so an ID may not contain dots (shop.web would own shop's web log), nor
+// anything else that isn't a letter, digit, - or _.
+func ValidateID(id string) error {
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return fmt.Errorf("app_id %q may only contain letters, digits, - and _", id)
+		}
+	}
+	return nil
+}
+
+// MaxInstances caps how far a single process type can be scaled.
+const MaxInstances = 10
+
+// ValidateScale checks an instance count for a process type, whether it
+// comes from reviewapps.yml or the scale API.
+func ValidateScale(procType string, count int) error {
+	if procType == "web" && count != 1 {
+		return fmt.Errorf("scale for web must be 1 (the web process owns the app's port)")
+	}
+	if count < 0 || count > MaxInstances {
+		return fmt.Errorf("scale for %q must be between 0 and %d", procType, MaxInstances)
+	}
+	return nil
+}
+
+// ProcessType returns the process type of an instance name (worker.2 → worker).
+func ProcessType(name string) string {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return name
+	}
+	if _, err := strconv.Atoi(name[i+1:]); err != nil {
+		return name
+	}
+	return name[:i]
+}
+
+// RollbackTarget returns the newest known-good release that isn't the commit
+// currently deployed, or nil if there is nothing to roll back to.
+func (s *AppState) RollbackTarget() *Release {
+	for i := range s.Releases {
+		if s.Releases[i].CommitSHA != s.CommitSHA {
+			return &s.Releases[i]
+		}
+	}
+	return nil
+}
+
+// CurrentRelease returns the recorded release that `current` points at, or
+// nil for a first deploy or an app from before release directories.
+func (s *AppState) CurrentRelease() *Release {
+	target, err := os.Readlink(filepath.Join(s.AppDir, "current"))
+	if err != nil {
+		return nil
+	}
+	for i := range s.Releases {
+		if s.Releases[i].ID == filepath.Base(target) {
+			return &s.Releases[i]
+		}
+	}
+	return nil
+}
+
+// ReleaseDir returns the checkout the app runs from: the release that
+// `current` points at, or the in-place repo for apps deployed before release
+// directories.
+func (s *AppState) ReleaseDir() string {
+	current := filepath.Join(s.AppDir, "current")
+	if _, err := os.Lstat(current); err == nil {
+		return current
+	}
+	return filepath.Join(s.AppDir, "repo")
+}
+
+// RepoDir returns the app's working directory: ReleaseDir plus the monorepo
+// app_path, if any.
+func (s *AppState) RepoDir() string {
+	if s.AppPath != "" && s.AppPath != "." {
+		return filepath.Join(s.ReleaseDir(), s.AppPath)
+	}
+	return s.ReleaseDir()
 }
@@ -14,6 +14,7 @@ type Store struct {
 	mu        sync.RWMutex
 	apps      map[string]*AppState
 	statePath string // path to state.json for persistence
+	onStatus  func(appID string, status Status, errMsg string)
 }
 
 func NewStore(statePath string) *Store {
@@ -72,17 +73,30 @@ func (s *Store) Count() int {
 	return len(s.apps)
 }
 
-func (s *Store) UpdateStatus(appID string, status Status, errMsg string) error {
+// OnStatusChange registers fn to be called after every UpdateStatus.
+func (s *Store) OnStatusChange(fn func(appID string, status Status, errMsg string)) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.onStatus = fn
+}
+
+func (s *Store) UpdateStatus(appID string, status Status, errMsg string) error {
+	s.mu.Lock()
 	state, ok := s.apps[appID]
 	if !ok {
+		s.mu.Unlock()
 		return fmt.Errorf("app %q not found", appID)
 	}
 	state.Status = status
 	state.Error = errMsg
 	state.UpdatedAt = time.Now()
 	s.persistLocked()
+	onStatus := s.onStatus
+	s.mu.Unlock()
+
+	if onStatus != nil {
+		onStatus(appID, status, errMsg)
+	}
 	return nil
 }
 
@@ -132,6 +146,95 @@ func (s *Store) SetProcess(appID string, proc ProcessInfo) error {
 	return nil
 }
 
+// RemoveProcess drops a process entry, e.g. an instance removed by scaling down.
+func (s *Store) RemoveProcess(appID, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.apps[appID]
+	if !ok {
+		return fmt.Errorf("app %q not found", appID)
+	}
+	delete(state.Processes, name)
+	if name == "web" {
+		state.PID = 0
+	}
+	state.UpdatedAt = time.Now()
+	s.persistLocked()
+	return nil
+}
+
+// SetScaleOverride records a runtime instance count for a process type.
+func (s *Store) SetScaleOverride(appID, procType string, count int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.apps[appID]
+	if !ok {
+		return fmt.Errorf("app %q not found", appID)
+	}
+	if state.ScaleOverrides == nil {
+		state.ScaleOverrides = make(map[string]int)
+	}
+	state.ScaleOverrides[procType] = count
+	state.UpdatedAt = time.Now()
+	s.persistLocked()
+	return nil
+}
+
+// RecordRelease adds a successful deploy to the front of the app's release
+// history, keeping at most keep entries.
+func (s *Store) RecordRelease(appID string, rel Release, keep int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.apps[appID]
+	if !ok {
+		return fmt.Errorf("app %q not found", appID)
+	}
+	releases := []Release{rel}
+	for _, r := range state.Releases {
+		if r.CommitSHA != rel.CommitSHA {
+			releases = append(releases, r)
+		}
+	}
+	if keep > 0 && len(releases) > keep {
+		releases = releases[:keep]
+	}
+	state.Releases = releases
+	state.UpdatedAt = time.Now()
+	s.persistLocked()
+	return nil
+}
+
+// MarkTeardownStep records a completed teardown step so a retried teardown
+// can skip it.
+func (s *Store) MarkTeardownStep(appID, step string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.apps[appID]
+	if !ok {
+		return fmt.Errorf("app %q not found", appID)
+	}
+	state.TeardownDone = append(state.TeardownDone, step)
+	state.UpdatedAt = time.Now()
+	s.persistLocked()
+	return nil
+}
+
+// RecordTeardownFailure counts a failed teardown attempt so the reaper can
+// back off before retrying it.
+func (s *Store) RecordTeardownFailure(appID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.apps[appID]
+	if !ok {
+		return fmt.Errorf("app %q not found", appID)
+	}
+	state.TeardownFailures++
+	state.TeardownFailedAt = time.Now()
+	state.UpdatedAt = state.TeardownFailedAt
+	s.persistLocked()
+	return nil
+}
+
 func (s *Store) ClearProcesses(appID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -146,13 +249,25 @@ func (s *Store) ClearProcesses(appID string) error {
 	return nil
 }
 
-func (s *Store) AppendLog(appID string, line string) {
+// AddBuild records the start of a build, keeping at most keep builds. It
+// returns the builds that fell off so their logs can be deleted.
+func (s *Store) AddBuild(appID string, b Build, keep int) ([]Build, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if state, ok := s.apps[appID]; ok {
-		state.BuildLog = append(state.BuildLog, line)
+	state, ok := s.apps[appID]
+	if !ok {
+		return nil, fmt.Errorf("app %q not found", appID)
+	}
+	builds := append([]Build{b}, state.Builds...)
+	var dropped []Build
+	if keep > 0 && len(builds) > keep {
+		dropped = builds[keep:]
+		builds = builds[:keep]
 	}
-	// Don't persist on every log line — too noisy. Build logs are ephemeral.
+	state.Builds = builds
+	state.UpdatedAt = time.Now()
+	s.persistLocked()
+	return dropped, nil
 }
 
 // load reads persisted state from disk. Called once at startup.
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Event is one audit record. Empty fields are left out of the log.
+type Event struct {
+	Time     time.Time `json:"ts"`
+	Action   string    `json:"action"` // e.g. console.start, console.end
+	AppID    string    `json:"app_id,omitempty"`
+	Session  string    `json:"session,omitempty"`
+	Remote   string    `json:"remote,omitempty"`
+	Token    string    `json:"token,omitempty"` // name of the API token used
+	Command  string    `json:"command,omitempty"`
+	ExitCode *int      `json:"exit_code,omitempty"`
+	Duration string    `json:"duration,omitempty"`
+	Detail   string    `json:"detail,omitempty"`
+}
+
+// Logger appends events as JSON lines to an audit log that, unlike process
+// and build logs, is never rotated or cleaned up by rad.
+type Logger struct {
+	path string
+	mu   sync.Mutex
+}
+
+func New(path string) *Logger {
+	return &Logger{path: path}
+}
+
+// Record appends an event. Failures are logged, not returned: auditing
+// must not break the action being audited.
+func (l *Logger) Record(ev Event) {
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	data, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("audit: %v", err)
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		log.Printf("audit: open %s: %v", l.path, err)
+		return
+	}
+	defer f.Close()
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		log.Printf("audit: write %s: %v", l.path, err)
+	}
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"path"
+
+	"github.com/reviewapps-dev/rad/internal/config"
+)
+
+// Scopes a token can hold.
+const (
+	ScopeRead     = "read"     // status, logs, disk usage
+	ScopeDeploy   = "deploy"   // deploy, rollback, restart, scale, sleep/wake
+	ScopeExec     = "exec"     // exec, exec jobs, console
+	ScopeTeardown = "teardown" // DELETE /apps/{id}
+	ScopeUpdate   = "update"   // self-update
+	ScopeAll      = "*"
+)
+
+var knownScopes = map[string]bool{
+	ScopeRead: true, ScopeDeploy: true, ScopeExec: true,
+	ScopeTeardown: true, ScopeUpdate: true, ScopeAll: true,
+}
+
+// Token is an authenticated API caller.
+type Token struct {
+	Name   string
+	Scopes []string
+	Apps   []string // app ID patterns; empty allows every app
+}
+
+// Has reports whether the token holds scope.
+func (t *Token) Has(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == ScopeAll {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsApp reports whether the token may act on appID.
+func (t *Token) AllowsApp(appID string) bool {
+	if len(t.Apps) == 0 {
+		return true
+	}
+	for _, pattern := range t.Apps {
+		if ok, _ := path.Match(pattern, appID); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Unrestricted reports whether the token isn't limited to some apps.
+func (t *Token) Unrestricted() bool {
+	return len(t.Apps) == 0
+}
+
+type entry struct {
+	digest [sha256.Size]byte
+	token  *Token
+}
+
+// Store holds the main token (all scopes) and the scoped tokens from config.
+type Store struct {
+	entries []entry
+}
+
+// NewStore builds the token store from config. Tokens without a secret or
+// with an unknown scope are an error.
+func NewStore(cfg *config.Config) (*Store, error) {
+	s := &Store{}
+	if cfg.Auth.Token != "" {
+		s.add(cfg.Auth.Token, &Token{Name: "main", Scopes: []string{ScopeAll}})
+	}
+	for i, tc := range cfg.Auth.Tokens {
+		name := tc.Name
+		if name == "" {
+			name = fmt.Sprintf("token-%d", i+1)
+		}
+		if tc.Token == "" {
+			return nil, fmt.Errorf("auth: token %s has no secret", name)
+		}
+		if len(tc.Scopes) == 0 {
+			return nil, fmt.Errorf("auth: token %s has no scopes", name)
+		}
+		for _, scope := range tc.Scopes {
+			if !knownScopes[scope] {
+				return nil, fmt.Errorf("auth: token %s: unknown scope %q", name, scope)
+			}
+		}
+		for _, pattern := range tc.Apps {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("auth: token %s: bad app pattern %q", name, pattern)
+			}
+		}
+		s.add(tc.Token, &Token{Name: name, Scopes: tc.Scopes, Apps: tc.Apps})
+	}
+	return s, nil
+}
+
+func (s *Store) add(secret string, t *Token) {
+	s.entries = append(s.entries, entry{digest: sha256.Sum256([]byte(secret)), token: t})
+}
+
+// Lookup returns the token for secret. Every token is compared, in constant
+// time over fixed-length digests, so timing reveals neither which token
+// matched nor how much of one did.
+func (s *Store) Lookup(secret string) (*Token, bool) {
+	if secret == "" {
+		return nil, false
+	}
+	digest := sha256.Sum256([]byte(secret))
+	var found *Token
+	for _, e := range s.entries {
+		if subtle.ConstantTimeCompare(digest[:], e.digest[:]) == 1 {
+			found = e.token
+		}
+	}
+	return found, found != nil
+}
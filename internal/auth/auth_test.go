@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/reviewapps-dev/rad/internal/config"
+)
+
+func TestTokenHas(t *testing.T) {
+	tests := []struct {
+		name   string
+		scopes []string
+		scope  string
+		want   bool
+	}{
+		{"exact scope", []string{ScopeRead}, ScopeRead, true},
+		{"one of several", []string{ScopeRead, ScopeExec}, ScopeExec, true},
+		{"wildcard", []string{ScopeAll}, ScopeTeardown, true},
+		{"wrong scope", []string{ScopeRead}, ScopeDeploy, false},
+		{"read does not imply exec", []string{ScopeRead, ScopeDeploy}, ScopeExec, false},
+		{"no scopes", nil, ScopeRead, false},
+		{"scope names are exact", []string{"deploy "}, ScopeDeploy, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := &Token{Name: "t", Scopes: tt.scopes}
+			if got := tok.Has(tt.scope); got != tt.want {
+				t.Errorf("Has(%q) with scopes %v = %v, want %v", tt.scope, tt.scopes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenAllowsApp(t *testing.T) {
+	tests := []struct {
+		name  string
+		apps  []string
+		appID string
+		want  bool
+	}{
+		{"unrestricted", nil, "anything", true},
+		{"exact match", []string{"shop-pr-1"}, "shop-pr-1", true},
+		{"prefix pattern", []string{"shop-*"}, "shop-pr-42", true},
+		{"second pattern", []string{"blog-*", "shop-*"}, "shop-pr-42", true},
+		{"single character", []string{"pr-?"}, "pr-7", true},
+		{"exact is not a prefix", []string{"shop"}, "shop-pr-1", false},
+		{"prefix pattern needs the prefix", []string{"shop-*"}, "myshop-pr-1", false},
+		{"pattern is anchored at the end", []string{"*-pr"}, "shop-pr-1", false},
+		{"single character is one character", []string{"pr-?"}, "pr-17", false},
+		{"star does not cross slashes", []string{"shop-*"}, "shop-x/y", false},
+		{"case sensitive", []string{"shop-*"}, "Shop-pr-1", false},
+		{"empty app ID", []string{"shop-*"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := &Token{Name: "t", Scopes: []string{ScopeAll}, Apps: tt.apps}
+			if got := tok.AllowsApp(tt.appID); got != tt.want {
+				t.Errorf("AllowsApp(%q) with apps %v = %v, want %v", tt.appID, tt.apps, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreLookup(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Auth.Token = "main-secret"
+	cfg.Auth.Tokens = []config.TokenConfig{
+		{Name: "ci", Token: "ci-secret", Scopes: []string{ScopeDeploy}, Apps: []string{"shop-*"}},
+		{Token: "reader-secret", Scopes: []string{ScopeRead}},
+	}
+	store, err := NewStore(cfg)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		secret   string
+		wantName string
+		wantOK   bool
+	}{
+		{"main token", "main-secret", "main", true},
+		{"scoped token", "ci-secret", "ci", true},
+		{"unnamed token", "reader-secret", "token-2", true},
+		{"unknown secret", "nope", "", false},
+		{"prefix of a secret", "ci-secre", "", false},
+		{"secret with suffix", "ci-secret2", "", false},
+		{"empty secret", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok, ok := store.Lookup(tt.secret)
+			if ok != tt.wantOK {
+				t.Fatalf("Lookup(%q) ok = %v, want %v", tt.secret, ok, tt.wantOK)
+			}
+			if ok && tok.Name != tt.wantName {
+				t.Errorf("Lookup(%q) = %s, want %s", tt.secret, tok.Name, tt.wantName)
+			}
+		})
+	}
+
+	ci, _ := store.Lookup("ci-secret")
+	if ci.Has(ScopeExec) || ci.Has(ScopeTeardown) {
+		t.Errorf("ci token has scopes beyond deploy: %v", ci.Scopes)
+	}
+	if ci.AllowsApp("blog-pr-1") {
+		t.Errorf("ci token allows an app outside shop-*")
+	}
+}
+
+func TestNewStoreRejectsBadTokens(t *testing.T) {
+	tests := []struct {
+		name  string
+		token config.TokenConfig
+	}{
+		{"no secret", config.TokenConfig{Name: "x", Scopes: []string{ScopeRead}}},
+		{"no scopes", config.TokenConfig{Name: "x", Token: "s"}},
+		{"unknown scope", config.TokenConfig{Name: "x", Token: "s", Scopes: []string{"admin"}}},
+		{"bad app pattern", config.TokenConfig{Name: "x", Token: "s", Scopes: []string{ScopeRead}, Apps: []string{"shop-["}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.Auth.Tokens = []config.TokenConfig{tt.token}
+			if _, err := NewStore(cfg); err == nil {
+				t.Errorf("NewStore accepted %+v", tt.token)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const requestKey = "request-signing-key"
+
+func TestVerifyRequest(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{"app_id":"shop-pr-1"}`)
+
+	tests := []struct {
+		name    string
+		modify  func(r *signedRequest) // applied after signing
+		verify  time.Time              // when the server checks it
+		wantErr string
+	}{
+		{"valid", nil, now, ""},
+		{"tampered body", func(r *signedRequest) { r.body = []byte(`{"app_id":"shop-pr-2"}`) }, now, "bad request signature"},
+		{"tampered path", func(r *signedRequest) { r.req.URL.Path = "/apps/shop-pr-2/deploy" }, now, "bad request signature"},
+		{"tampered query", func(r *signedRequest) { r.req.URL.RawQuery = "force=1" }, now, "bad request signature"},
+		{"tampered method", func(r *signedRequest) { r.req.Method = "DELETE" }, now, "bad request signature"},
+		{"timestamp changed", func(r *signedRequest) {
+			r.req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix()+1, 10))
+		}, now, "bad request signature"},
+		{"nonce changed", func(r *signedRequest) { r.req.Header.Set(HeaderNonce, "other") }, now, "bad request signature"},
+		{"signed with another key", func(r *signedRequest) { SignRequest(r.req, "other-key", r.body, now) }, now, "bad request signature"},
+		{"at the edge of skew", nil, now.Add(MaxSkew), ""},
+		{"at the edge of skew ahead", nil, now.Add(-MaxSkew), ""},
+		{"too old", nil, now.Add(MaxSkew + time.Second), "off"},
+		{"too far in the future", nil, now.Add(-MaxSkew - time.Second), "off"},
+		{"missing signature", func(r *signedRequest) { r.req.Header.Del(HeaderSignature) }, now, "missing or malformed"},
+		{"missing nonce", func(r *signedRequest) { r.req.Header.Del(HeaderNonce) }, now, "missing or malformed"},
+		{"non-hex signature", func(r *signedRequest) { r.req.Header.Set(HeaderSignature, "zz") }, now, "missing or malformed"},
+		{"non-numeric timestamp", func(r *signedRequest) { r.req.Header.Set(HeaderTimestamp, "yesterday") }, now, "malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newSignedRequest(body, now)
+			if tt.modify != nil {
+				tt.modify(r)
+			}
+			err := VerifyRequest(r.req, requestKey, r.body, NewNonceCache(), tt.verify)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("VerifyRequest: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("VerifyRequest accepted the request, want error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyRequestReplay(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	body := []byte(`{}`)
+	nonces := NewNonceCache()
+
+	r := newSignedRequest(body, now)
+	if err := VerifyRequest(r.req, requestKey, body, nonces, now); err != nil {
+		t.Fatalf("first request: %v", err)
+	}
+	if err := VerifyRequest(r.req, requestKey, body, nonces, now.Add(time.Second)); err == nil || !strings.Contains(err.Error(), "replayed") {
+		t.Errorf("replayed request: err = %v, want replayed", err)
+	}
+	// Still refused for as long as the timestamp is accepted
+	if err := VerifyRequest(r.req, requestKey, body, nonces, now.Add(MaxSkew)); err == nil {
+		t.Errorf("replay at the edge of skew was accepted")
+	}
+
+	// A fresh signature of the same request gets a new nonce
+	again := newSignedRequest(body, now)
+	if err := VerifyRequest(again.req, requestKey, body, nonces, now); err != nil {
+		t.Errorf("re-signed request: %v", err)
+	}
+
+	// A bad signature doesn't use up the nonce
+	forged := newSignedRequest(body, now)
+	if err := VerifyRequest(forged.req, "other-key", body, nonces, now); err == nil {
+		t.Fatal("request verified with the wrong key")
+	}
+	if err := VerifyRequest(forged.req, requestKey, body, nonces, now); err != nil {
+		t.Errorf("nonce used up by a failed verification: %v", err)
+	}
+}
+
+func TestNonceCacheForgetsOldNonces(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	c := NewNonceCache()
+	if !c.Add("n", now) {
+		t.Fatal("new nonce reported as seen")
+	}
+	if c.Add("n", now.Add(2*MaxSkew)) {
+		t.Error("nonce forgotten while its timestamp could still be accepted")
+	}
+	if !c.Add("n", now.Add(2*MaxSkew+MaxSkew+time.Second)) {
+		t.Error("nonce remembered long after its timestamp expired")
+	}
+}
+
+type signedRequest struct {
+	req  *http.Request
+	body []byte
+}
+
+func newSignedRequest(body []byte, now time.Time) *signedRequest {
+	req := httptest.NewRequest("POST", "/apps/shop-pr-1/deploy?dry_run=0", nil)
+	SignRequest(req, requestKey, body, now)
+	return &signedRequest{req: req, body: body}
+}
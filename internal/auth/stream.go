@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// streamTokenPrefix marks a signed stream token; it's also the version of
+// the format.
+const streamTokenPrefix = "v1."
+
+// StreamClaims is what a signed stream token grants: reading the given log
+// stream types of one app until it expires.
+type StreamClaims struct {
+	App     string   `json:"app"`
+	Types   []string `json:"types"` // build, runtime, all
+	Expires int64    `json:"exp"`   // Unix seconds
+	Subject string   `json:"sub,omitempty"`
+}
+
+// AllowsType reports whether the claims cover the stream type.
+func (c *StreamClaims) AllowsType(typ string) bool {
+	for _, t := range c.Types {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStreamToken reports whether token looks like a signed stream token.
+func IsStreamToken(token string) bool {
+	return strings.HasPrefix(token, streamTokenPrefix)
+}
+
+// VerifyStreamToken checks a token of the form
+// v1.<base64url(claims JSON)>.<base64url(HMAC-SHA256(key, "v1.<claims>"))>
+// and returns its claims if the signature matches and it hasn't expired.
+func VerifyStreamToken(key, token string, now time.Time) (*StreamClaims, error) {
+	if key == "" {
+		return nil, fmt.Errorf("signed stream tokens are not enabled")
+	}
+	if !IsStreamToken(token) {
+		return nil, fmt.Errorf("not a signed stream token")
+	}
+	signed, sig, ok := strings.Cut(token[len(streamTokenPrefix):], ".")
+	if !ok {
+		return nil, fmt.Errorf("malformed stream token")
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return nil, fmt.Errorf("malformed stream token signature")
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(streamTokenPrefix + signed))
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return nil, fmt.Errorf("bad stream token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(signed)
+	if err != nil {
+		return nil, fmt.Errorf("malformed stream token claims")
+	}
+	var claims StreamClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed stream token claims: %w", err)
+	}
+	if claims.App == "" || claims.Expires == 0 {
+		return nil, fmt.Errorf("stream token is missing app or exp")
+	}
+	if now.Unix() >= claims.Expires {
+		return nil, fmt.Errorf("stream token expired")
+	}
+	return &claims, nil
+}
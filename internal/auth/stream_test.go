@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+const streamKey = "stream-signing-key"
+
+// mint builds a token the way the web app does.
+func mint(key string, claims any) string {
+	payload, _ := json.Marshal(claims)
+	return mintRaw(key, base64.RawURLEncoding.EncodeToString(payload))
+}
+
+func mintRaw(key, signed string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(streamTokenPrefix + signed))
+	return streamTokenPrefix + signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifyStreamToken(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	valid := StreamClaims{App: "shop-pr-1", Types: []string{"build", "runtime"}, Expires: now.Add(time.Minute).Unix(), Subject: "user-7"}
+	good := mint(streamKey, valid)
+
+	// Swap in other claims but keep the original signature
+	_, goodSig, _ := strings.Cut(good[len(streamTokenPrefix):], ".")
+	otherClaims, _ := json.Marshal(StreamClaims{App: "shop-pr-2", Types: []string{"build"}, Expires: valid.Expires})
+	swapped := streamTokenPrefix + base64.RawURLEncoding.EncodeToString(otherClaims) + "." + goodSig
+
+	tests := []struct {
+		name    string
+		key     string
+		token   string
+		now     time.Time
+		wantErr string
+	}{
+		{"valid", streamKey, good, now, ""},
+		{"expired", streamKey, good, now.Add(time.Minute), "expired"},
+		{"long expired", streamKey, good, now.Add(24 * time.Hour), "expired"},
+		{"signed with another key", streamKey, mint("other-key", valid), now, "bad stream token signature"},
+		{"claims swapped under a valid signature", streamKey, swapped, now, "bad stream token signature"},
+		{"signature truncated", streamKey, good[:len(good)-4], now, "signature"},
+		{"no signature", streamKey, good[:strings.LastIndex(good, ".")], now, "malformed"},
+		{"not base64 signature", streamKey, good + "!", now, "malformed"},
+		{"wrong version", streamKey, "v2" + good[2:], now, "not a signed stream token"},
+		{"signed claims that aren't JSON", streamKey, mintRaw(streamKey, base64.RawURLEncoding.EncodeToString([]byte("nope"))), now, "malformed"},
+		{"missing app", streamKey, mint(streamKey, StreamClaims{Types: []string{"build"}, Expires: valid.Expires}), now, "missing app or exp"},
+		{"missing exp", streamKey, mint(streamKey, StreamClaims{App: "shop-pr-1", Types: []string{"build"}}), now, "missing app or exp"},
+		{"signing disabled", "", good, now, "not enabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := VerifyStreamToken(tt.key, tt.token, tt.now)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("VerifyStreamToken: %v", err)
+				}
+				if claims.App != valid.App || claims.Subject != valid.Subject || claims.Expires != valid.Expires {
+					t.Errorf("claims = %+v, want %+v", claims, valid)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("VerifyStreamToken accepted the token, want error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// The caller checks the claims against the app and stream type requested.
+func TestStreamClaimsScope(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	token := mint(streamKey, StreamClaims{App: "shop-pr-1", Types: []string{"build"}, Expires: now.Add(time.Minute).Unix()})
+	claims, err := VerifyStreamToken(streamKey, token, now)
+	if err != nil {
+		t.Fatalf("VerifyStreamToken: %v", err)
+	}
+
+	if claims.App == "shop-pr-2" {
+		t.Errorf("token for shop-pr-1 claims shop-pr-2")
+	}
+	for typ, want := range map[string]bool{"build": true, "runtime": false, "all": false, "": false, "Build": false} {
+		if got := claims.AllowsType(typ); got != want {
+			t.Errorf("AllowsType(%q) = %v, want %v", typ, got, want)
+		}
+	}
+}
+
+func TestIsStreamToken(t *testing.T) {
+	for token, want := range map[string]bool{
+		mint(streamKey, StreamClaims{App: "a", Expires: 1}): true,
+		"v1.":          true,
+		"static-token": false,
+		"v2.abc.def":   false,
+		"":             false,
+	} {
+		if got := IsStreamToken(token); got != want {
+			t.Errorf("IsStreamToken(%q) = %v, want %v", token, got, want)
+		}
+	}
+}
@@ -12,6 +12,7 @@ type Job struct {
 }
 
 type Queue struct {
+	mu     sync.Mutex // serializes Enqueue so a batch is all or nothing
 	ch     chan Job
 	wg     sync.WaitGroup
 	cancel context.CancelFunc
@@ -47,13 +48,18 @@ func (q *Queue) Start(ctx context.Context) {
 	}()
 }
 
-func (q *Queue) Enqueue(job Job) bool {
-	select {
-	case q.ch <- job:
-		return true
-	default:
+// Enqueue queues the jobs in order, or none of them if there isn't room for
+// all of them. It never blocks.
+func (q *Queue) Enqueue(jobs ...Job) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if cap(q.ch)-len(q.ch) < len(jobs) {
 		return false
 	}
+	for _, job := range jobs {
+		q.ch <- job
+	}
+	return true
 }
 
 func (q *Queue) Stop() {
@@ -50,8 +50,15 @@ type SiteConfig struct {
 	Subdomain string // e.g. "pr-42-myapp" → pr-42-myapp.srv.reviewapps.dev
 	Port      int
 	LogDir    string
+
+	// WakeAddr, when set, proxies the site to rad's wake listener instead of
+	// the app's port. Used while the app is sleeping.
+	WakeAddr string
 }
 
+// WakeHeader carries the app ID on requests Caddy forwards to the wake listener.
+const WakeHeader = "X-Rad-Wake-App"
+
 // WriteSiteConfig writes a per-app Caddy config file.
 // The file is named {app_id}.caddy and contains a reverse proxy block.
 func (m *Manager) WriteSiteConfig(cfg SiteConfig) error {
@@ -65,19 +72,30 @@ func (m *Manager) WriteSiteConfig(cfg SiteConfig) error {
 
 	hostname := cfg.Subdomain + ".srv.reviewapps.dev"
 
+	proxy := fmt.Sprintf("reverse_proxy localhost:%d", cfg.Port)
+	if cfg.WakeAddr != "" {
+		proxy = fmt.Sprintf(`reverse_proxy %s {
+		header_up %s %s
+	}`, cfg.WakeAddr, WakeHeader, cfg.AppID)
+	}
+
 	content := fmt.Sprintf(`%s {
-	reverse_proxy localhost:%d
+	%s
 	log {
 		output file %s
 	}
 }
-`, hostname, cfg.Port, filepath.Join(cfg.LogDir, cfg.AppID+".access.log"))
+`, hostname, proxy, filepath.Join(cfg.LogDir, cfg.AppID+".access.log"))
 
 	path := filepath.Join(m.ConfigDir, cfg.AppID+".caddy")
 	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
 		return fmt.Errorf("caddy: write site config: %w", err)
 	}
 
+	if cfg.WakeAddr != "" {
+		log.Printf("caddy: wrote site config %s → %s (wake listener)", path, hostname)
+		return nil
+	}
 	log.Printf("caddy: wrote site config %s → %s:%d", path, hostname, cfg.Port)
 	return nil
 }
@@ -7,17 +7,24 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/reviewapps-dev/rad/internal/auth"
 )
 
 type Client struct {
 	httpClient *http.Client
 	token      string
+	signingKey string
 }
 
-func NewClient(token string) *Client {
+// NewClient returns a client that authenticates with token and, when
+// signingKey is set, signs every request the way rad checks signed API
+// requests.
+func NewClient(token, signingKey string) *Client {
 	return &Client{
 		httpClient: &http.Client{Timeout: 10 * time.Second},
 		token:      token,
+		signingKey: signingKey,
 	}
 }
 
@@ -28,6 +35,9 @@ type StatusPayload struct {
 	URL       string `json:"url,omitempty"`
 	Error     string `json:"error,omitempty"`
 	CommitSHA string `json:"commit_sha,omitempty"`
+
+	// Set on rollbacks: the commit that failed and was rolled back from
+	RollbackFrom string `json:"rollback_from,omitempty"`
 }
 
 func (c *Client) SendStatus(callbackURL string, payload StatusPayload) error {
@@ -64,10 +74,7 @@ func (c *Client) SendLogs(logsURL string, payload LogPayload) error {
 	if err != nil {
 		return nil
 	}
-	req.Header.Set("Content-Type", "application/json")
-	if c.token != "" {
-		req.Header.Set("Authorization", "Bearer "+c.token)
-	}
+	c.setHeaders(req, body)
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil
@@ -77,6 +84,16 @@ func (c *Client) SendLogs(logsURL string, payload LogPayload) error {
 	return nil
 }
 
+func (c *Client) setHeaders(req *http.Request, body []byte) {
+	req.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	if c.signingKey != "" {
+		auth.SignRequest(req, c.signingKey, body, time.Now())
+	}
+}
+
 // postWithRetry attempts a POST up to 3 times with exponential backoff.
 // Never returns an error — callbacks are best-effort and must not fail deploys.
 func (c *Client) postWithRetry(url string, body []byte) {
@@ -92,10 +109,7 @@ func (c *Client) postWithRetry(url string, body []byte) {
 			log.Printf("callback: POST %s attempt %d failed to build request: %v", url, attempt+1, err)
 			continue
 		}
-		req.Header.Set("Content-Type", "application/json")
-		if c.token != "" {
-			req.Header.Set("Authorization", "Bearer "+c.token)
-		}
+		c.setHeaders(req, body)
 		resp, err := c.httpClient.Do(req)
 		if err != nil {
 			log.Printf("callback: POST %s attempt %d failed: %v", url, attempt+1, err)
@@ -0,0 +1,119 @@
+package callback
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/auth"
+)
+
+const signingKey = "request-signing-key"
+
+// verifyingServer checks every request the way the web app does and records
+// the outcome.
+type verifyingServer struct {
+	mu      sync.Mutex
+	nonces  *auth.NonceCache
+	errs    []error
+	bodies  [][]byte
+	headers []http.Header
+}
+
+func (v *verifyingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	err := auth.VerifyRequest(r, signingKey, body, v.nonces, time.Now())
+
+	v.mu.Lock()
+	v.errs = append(v.errs, err)
+	v.bodies = append(v.bodies, body)
+	v.headers = append(v.headers, r.Header.Clone())
+	v.mu.Unlock()
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func TestClientSignsRequests(t *testing.T) {
+	v := &verifyingServer{nonces: auth.NewNonceCache()}
+	srv := httptest.NewServer(v)
+	defer srv.Close()
+
+	c := NewClient("api-key", signingKey)
+	if err := c.SendStatus(srv.URL+"/apps/shop-pr-1/status?attempt=1", StatusPayload{AppID: "shop-pr-1", Status: "running"}); err != nil {
+		t.Fatalf("SendStatus: %v", err)
+	}
+	if err := c.SendLogs(srv.URL+"/apps/shop-pr-1/logs", LogPayload{AppID: "shop-pr-1", Lines: []string{"a", "b"}}); err != nil {
+		t.Fatalf("SendLogs: %v", err)
+	}
+
+	if len(v.errs) != 2 {
+		t.Fatalf("server got %d requests, want 2", len(v.errs))
+	}
+	for i, err := range v.errs {
+		if err != nil {
+			t.Errorf("request %d failed verification: %v", i, err)
+		}
+		if got := v.headers[i].Get("Authorization"); got != "Bearer api-key" {
+			t.Errorf("request %d Authorization = %q", i, got)
+		}
+	}
+	if v.headers[0].Get(auth.HeaderNonce) == v.headers[1].Get(auth.HeaderNonce) {
+		t.Errorf("two requests share a nonce")
+	}
+	var status StatusPayload
+	if err := json.Unmarshal(v.bodies[0], &status); err != nil || status.Status != "running" {
+		t.Errorf("status body = %s", v.bodies[0])
+	}
+}
+
+func TestClientSignatureCoversBody(t *testing.T) {
+	// Re-sending the callback's headers with another body must fail
+	v := &verifyingServer{nonces: auth.NewNonceCache()}
+	srv := httptest.NewServer(v)
+	defer srv.Close()
+
+	c := NewClient("api-key", signingKey)
+	c.SendLogs(srv.URL+"/logs", LogPayload{AppID: "shop-pr-1", Lines: []string{"ok"}})
+	if len(v.errs) != 1 || v.errs[0] != nil {
+		t.Fatalf("signed request failed verification: %v", v.errs)
+	}
+
+	tampered := []byte(`{"app_id":"shop-pr-1","lines":["forged"]}`)
+	req, _ := http.NewRequest("POST", srv.URL+"/logs", bytes.NewReader(tampered))
+	req.Header = v.headers[0].Clone()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("tampered body got %d, want 401", resp.StatusCode)
+	}
+	if err := v.errs[1]; err == nil || !strings.Contains(err.Error(), "bad request signature") {
+		t.Errorf("tampered body: err = %v, want bad request signature", err)
+	}
+}
+
+func TestClientWithoutKeyDoesNotSign(t *testing.T) {
+	v := &verifyingServer{nonces: auth.NewNonceCache()}
+	srv := httptest.NewServer(v)
+	defer srv.Close()
+
+	NewClient("api-key", "").SendLogs(srv.URL+"/logs", LogPayload{AppID: "shop-pr-1"})
+	if len(v.headers) != 1 {
+		t.Fatalf("server got %d requests, want 1", len(v.headers))
+	}
+	if v.headers[0].Get(auth.HeaderSignature) != "" {
+		t.Errorf("request signed without a signing key")
+	}
+}
@@ -0,0 +1,149 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves a TLS certificate, and optionally a client CA pool, from
+// files, picking up new versions when the files change or Reload is called
+// (on SIGHUP). A failed reload keeps serving the previous certificate.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+	interval time.Duration
+	done     chan struct{}
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	stamp     string // mod times of the files at the last reload
+}
+
+// New loads the certificate, key and (if caFile is set) client CA bundle,
+// and checks them for changes every interval once started.
+func New(certFile, keyFile, caFile string, interval time.Duration) (*Reloader, error) {
+	r := &Reloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		caFile:   caFile,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reloader) Start() {
+	go r.loop()
+	log.Printf("tls: watching %s (interval=%s)", r.certFile, r.interval)
+}
+
+func (r *Reloader) Stop() {
+	close(r.done)
+}
+
+func (r *Reloader) loop() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.mu.RLock()
+			changed := r.modStamp() != r.stamp
+			r.mu.RUnlock()
+			if changed {
+				if err := r.Reload(); err != nil {
+					log.Printf("tls: %v (keeping previous certificate)", err)
+				}
+			}
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// Reload reads the files again.
+func (r *Reloader) Reload() error {
+	stamp := r.modStamp()
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("tls: load %s: %w", r.certFile, err)
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return fmt.Errorf("tls: read client CA: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tls: no certificates in %s", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.clientCAs, r.stamp = &cert, pool, stamp
+	r.mu.Unlock()
+
+	if leaf, err := x509.ParseCertificate(cert.Certificate[0]); err == nil {
+		log.Printf("tls: loaded certificate for %s (expires %s)", leaf.Subject.CommonName, leaf.NotAfter.Format(time.DateOnly))
+	}
+	return nil
+}
+
+// modStamp summarizes the files' mod times, to notice when any changes.
+func (r *Reloader) modStamp() string {
+	var stamp string
+	for _, path := range []string{r.certFile, r.keyFile, r.caFile} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			stamp += fmt.Sprintf("%d/%d;", info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	return stamp
+}
+
+// RequiresClientCerts reports whether a client CA is configured.
+func (r *Reloader) RequiresClientCerts() bool {
+	return r.caFile != ""
+}
+
+// TLSConfig returns a server config that always uses the current
+// certificate and client CAs. Client certificates are verified when given
+// but not demanded at the handshake, so endpoints that browsers use (log
+// streams, /health) keep working; handlers that need one check for it.
+func (r *Reloader) TLSConfig() *tls.Config {
+	cfg := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.cert, nil
+		},
+	}
+	if r.caFile == "" {
+		return cfg
+	}
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		c := cfg.Clone()
+		c.GetConfigForClient = nil
+		c.ClientAuth = tls.VerifyClientCertIfGiven
+		r.mu.RLock()
+		c.ClientCAs = r.clientCAs
+		r.mu.RUnlock()
+		return c, nil
+	}
+	return cfg
+}
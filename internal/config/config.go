@@ -15,6 +15,13 @@ type Config struct {
 	Paths    PathsConfig    `toml:"paths"`
 	Caddy    CaddyConfig    `toml:"caddy"`
 	Defaults DefaultsConfig `toml:"defaults"`
+	Deploy   DeployConfig   `toml:"deploy"`
+	Sleep    SleepConfig    `toml:"sleep"`
+	Limits   LimitsConfig   `toml:"limits"`
+	Cleanup  CleanupConfig  `toml:"cleanup"`
+	Logs     LogsConfig     `toml:"logs"`
+	Console  ConsoleConfig  `toml:"console"`
+	Exec     ExecConfig     `toml:"exec"`
 
 	// Runtime flags (not from TOML)
 	Dev bool `toml:"-"`
@@ -27,12 +34,42 @@ type CaddyConfig struct {
 
 type ServerConfig struct {
 	Listen string `toml:"listen"`
-	TLS    bool   `toml:"tls"`
+	// TLS serves the API over HTTPS with CertFile and KeyFile, reloaded when
+	// they change or on SIGHUP. Without them rad serves plain HTTP.
+	TLS      bool   `toml:"tls"`
+	CertFile string `toml:"cert_file"`
+	KeyFile  string `toml:"key_file"`
+	// ClientCAFile makes authenticated endpoints require a client
+	// certificate signed by one of these CAs (mTLS).
+	ClientCAFile string `toml:"client_ca_file"`
 }
 
 type AuthConfig struct {
 	Token       string `toml:"-"` // auth token, set via --token flag
 	StreamToken string `toml:"-"` // read-only token for WebSocket log streaming
+	// Tokens are extra API tokens with limited scopes. TokensFile holds more,
+	// as [[tokens]] tables, so secrets can live outside config.toml.
+	Tokens     []TokenConfig `toml:"tokens"`
+	TokensFile string        `toml:"tokens_file"`
+	// StreamSigningKey enables HMAC-signed, per-app stream tokens minted by
+	// the web app. DisableStreamToken turns off the static StreamToken.
+	StreamSigningKey   string `toml:"stream_signing_key"`
+	DisableStreamToken bool   `toml:"disable_stream_token"`
+	// RequestSigningKey enables HMAC signatures on API requests and on
+	// callbacks to the web app. RequireSignedRequests rejects unsigned API
+	// requests; otherwise only requests that carry a signature are checked.
+	RequestSigningKey     string `toml:"request_signing_key"`
+	RequireSignedRequests bool   `toml:"require_signed_requests"`
+}
+
+// TokenConfig is a scoped API token. Scopes are read, deploy, exec,
+// teardown, update, or * for all. Apps restricts it to app IDs matching
+// any of the patterns (path.Match syntax); empty means every app.
+type TokenConfig struct {
+	Name   string   `toml:"name"`
+	Token  string   `toml:"token"`
+	Scopes []string `toml:"scopes"`
+	Apps   []string `toml:"apps"`
 }
 
 type APIConfig struct {
@@ -44,11 +81,79 @@ type APIConfig struct {
 type PathsConfig struct {
 	AppsDir string `toml:"apps_dir"`
 	LogDir  string `toml:"log_dir"`
+	// AuditLog is the append-only log of console sessions. The cleanup job
+	// leaves it alone even if it's inside LogDir.
+	AuditLog string `toml:"audit_log"`
 }
 
 type DefaultsConfig struct {
 	RubyVersion     string `toml:"ruby_version"`
 	DatabaseAdapter string `toml:"database_adapter"`
+	// TTLHours tears apps down this long after their first deploy unless a
+	// deploy request sets ttl or expires_at; redeploys keep the expiry. Zero
+	// keeps apps until deleted.
+	TTLHours int `toml:"ttl_hours"`
+}
+
+type DeployConfig struct {
+	// AutoRollback redeploys the last known-good release when a redeploy fails.
+	AutoRollback bool `toml:"auto_rollback"`
+	// KeepReleases is how many successful releases are remembered for rollback.
+	KeepReleases int `toml:"keep_releases"`
+}
+
+type SleepConfig struct {
+	// Enabled stops idle apps and wakes them on the next request. Needs Caddy,
+	// whose access logs are used to measure idle time.
+	Enabled bool `toml:"enabled"`
+	// IdleMinutes is how long an app can go without requests before it sleeps.
+	IdleMinutes int `toml:"idle_minutes"`
+	// WakeListen is where rad accepts requests for sleeping apps from Caddy.
+	WakeListen string `toml:"wake_listen"`
+	// WakeTimeout is how many seconds a woken app has to pass its health check.
+	WakeTimeout int `toml:"wake_timeout"`
+}
+
+// LimitsConfig caps what the host takes on. Deploys that would exceed a
+// limit are rejected. Zero disables a limit.
+type LimitsConfig struct {
+	MinFreeDiskMB   int `toml:"min_free_disk_mb"`   // free space required under AppsDir
+	MinFreeMemoryMB int `toml:"min_free_memory_mb"` // MemAvailable required (Linux only)
+	MaxApps         int `toml:"max_apps"`
+	// EvictSleeping tears down the least recently used sleeping app to make
+	// room when the disk or app limit is hit.
+	EvictSleeping bool `toml:"evict_sleeping"`
+}
+
+type CleanupConfig struct {
+	// IntervalMinutes is how often the disk cleanup job runs. Zero disables it.
+	IntervalMinutes int `toml:"interval_minutes"`
+	// MaxAgeHours is how old tmp/cache files, unreferenced assets and
+	// orphaned logs must be before they're removed.
+	MaxAgeHours int `toml:"max_age_hours"`
+}
+
+// LogsConfig controls rotation of process logs ({app_id}.log, {app_id}.{name}.log)
+// and how many build logs ({app_id}/builds/{id}.log) are kept.
+type LogsConfig struct {
+	MaxSizeMB   int  `toml:"max_size_mb"`   // rotate at this size; 0 disables
+	MaxAgeHours int  `toml:"max_age_hours"` // rotate after this long; 0 disables
+	Keep        int  `toml:"keep"`          // rotated segments to keep per log
+	Compress    bool `toml:"compress"`      // gzip rotated segments
+	KeepBuilds  int  `toml:"keep_builds"`   // build logs to keep per app; 0 keeps all
+}
+
+// ConsoleConfig controls interactive sessions on GET /apps/{id}/console.
+type ConsoleConfig struct {
+	Command     string `toml:"command"`      // run when the client doesn't pass one
+	IdleMinutes int    `toml:"idle_minutes"` // close sessions without input or output for this long
+}
+
+// ExecConfig caps background exec jobs (POST /apps/{id}/exec with async).
+// Zero disables a cap.
+type ExecConfig struct {
+	MaxJobsPerApp int `toml:"max_jobs_per_app"` // running jobs per app
+	MaxJobs       int `toml:"max_jobs"`         // running jobs on the host
 }
 
 func DefaultDev() *Config {
@@ -64,8 +169,9 @@ func DefaultDev() *Config {
 			Endpoint: "http://localhost:3000/api/v1",
 		},
 		Paths: PathsConfig{
-			AppsDir: filepath.Join(home, ".reviewapps", "apps"),
-			LogDir:  filepath.Join(home, ".reviewapps", "log"),
+			AppsDir:  filepath.Join(home, ".reviewapps", "apps"),
+			LogDir:   filepath.Join(home, ".reviewapps", "log"),
+			AuditLog: filepath.Join(home, ".reviewapps", "audit.log"),
 		},
 		Caddy: CaddyConfig{
 			Enabled:   false, // No Caddy in dev mode — access apps via localhost:{port}
@@ -75,6 +181,35 @@ func DefaultDev() *Config {
 			RubyVersion:     "3.4.1",
 			DatabaseAdapter: "sqlite",
 		},
+		Deploy: DeployConfig{
+			AutoRollback: true,
+			KeepReleases: 5,
+		},
+		Sleep: SleepConfig{
+			Enabled:     false,
+			IdleMinutes: 60,
+			WakeListen:  "127.0.0.1:7891",
+			WakeTimeout: 60,
+		},
+		Cleanup: CleanupConfig{
+			IntervalMinutes: 60,
+			MaxAgeHours:     24,
+		},
+		Logs: LogsConfig{
+			MaxSizeMB:   50,
+			MaxAgeHours: 24,
+			Keep:        5,
+			Compress:    true,
+			KeepBuilds:  20,
+		},
+		Console: ConsoleConfig{
+			Command:     "bin/rails console",
+			IdleMinutes: 15,
+		},
+		Exec: ExecConfig{
+			MaxJobsPerApp: 2,
+			MaxJobs:       8,
+		},
 	}
 }
 
@@ -83,15 +218,16 @@ func DefaultProd() *Config {
 		Dev: false,
 		Server: ServerConfig{
 			Listen: "0.0.0.0:7890",
-			TLS:    true,
+			TLS:    false, // needs cert_file and key_file
 		},
 		Auth: AuthConfig{},
 		API: APIConfig{
 			Endpoint: "https://reviewapps.dev/api/v1",
 		},
 		Paths: PathsConfig{
-			AppsDir: "/opt/reviewapps/apps",
-			LogDir:  "/opt/reviewapps/log",
+			AppsDir:  "/opt/reviewapps/apps",
+			LogDir:   "/opt/reviewapps/log",
+			AuditLog: "/opt/reviewapps/audit.log",
 		},
 		Caddy: CaddyConfig{
 			Enabled:   true,
@@ -101,6 +237,38 @@ func DefaultProd() *Config {
 			RubyVersion:     "3.4.1",
 			DatabaseAdapter: "sqlite",
 		},
+		Deploy: DeployConfig{
+			AutoRollback: true,
+			KeepReleases: 5,
+		},
+		Sleep: SleepConfig{
+			Enabled:     false,
+			IdleMinutes: 60,
+			WakeListen:  "127.0.0.1:7891",
+			WakeTimeout: 60,
+		},
+		Limits: LimitsConfig{
+			MinFreeDiskMB: 1024,
+		},
+		Cleanup: CleanupConfig{
+			IntervalMinutes: 60,
+			MaxAgeHours:     24,
+		},
+		Logs: LogsConfig{
+			MaxSizeMB:   50,
+			MaxAgeHours: 24,
+			Keep:        5,
+			Compress:    true,
+			KeepBuilds:  20,
+		},
+		Console: ConsoleConfig{
+			Command:     "bin/rails console",
+			IdleMinutes: 15,
+		},
+		Exec: ExecConfig{
+			MaxJobsPerApp: 2,
+			MaxJobs:       8,
+		},
 	}
 }
 
@@ -120,11 +288,21 @@ func Load(path string, dev bool) (*Config, error) {
 		}
 	}
 
+	if cfg.Auth.TokensFile != "" {
+		var file struct {
+			Tokens []TokenConfig `toml:"tokens"`
+		}
+		if _, err := toml.DecodeFile(cfg.Auth.TokensFile, &file); err != nil {
+			return nil, fmt.Errorf("config: parse %s: %w", cfg.Auth.TokensFile, err)
+		}
+		cfg.Auth.Tokens = append(cfg.Auth.Tokens, file.Tokens...)
+	}
+
 	return cfg, nil
 }
 
 func (c *Config) EnsureDirs() error {
-	for _, dir := range []string{c.Paths.AppsDir, c.Paths.LogDir} {
+	for _, dir := range []string{c.Paths.AppsDir, c.Paths.LogDir, filepath.Dir(c.Paths.AuditLog)} {
 		if err := os.MkdirAll(dir, 0755); err != nil {
 			return fmt.Errorf("config: create dir %s: %w", dir, err)
 		}
@@ -3,6 +3,7 @@ package deploy
 import (
 	"context"
 	"fmt"
+	"log"
 	"strings"
 	"sync"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"github.com/reviewapps-dev/rad/internal/logging"
 	"github.com/reviewapps-dev/rad/internal/logstream"
 	"github.com/reviewapps-dev/rad/internal/port"
+	"github.com/reviewapps-dev/rad/internal/supervisor"
 )
 
 type Pipeline struct {
@@ -23,15 +25,19 @@ type Pipeline struct {
 	ports  *port.Allocator
 	caddy  *caddy.Manager
 	hub    *logstream.Hub
+	events *logstream.Events
+	sup    *supervisor.Supervisor
 }
 
-func NewPipeline(cfg *config.Config, store *app.Store, ports *port.Allocator, cm *caddy.Manager, hub *logstream.Hub) *Pipeline {
+func NewPipeline(cfg *config.Config, store *app.Store, ports *port.Allocator, cm *caddy.Manager, hub *logstream.Hub, events *logstream.Events, sup *supervisor.Supervisor) *Pipeline {
 	return &Pipeline{
-		cfg:   cfg,
-		store: store,
-		ports: ports,
-		caddy: cm,
-		hub:   hub,
+		cfg:    cfg,
+		store:  store,
+		ports:  ports,
+		caddy:  cm,
+		hub:    hub,
+		events: events,
+		sup:    sup,
 	}
 }
 
@@ -40,37 +46,73 @@ func (p *Pipeline) AddStep(s Step) {
 }
 
 func (p *Pipeline) Run(ctx context.Context, state *app.AppState, redeploy ...bool) error {
+	isRedeploy := len(redeploy) > 0 && redeploy[0]
+	return p.run(ctx, state, isRedeploy, "")
+}
+
+// Rollback returns to the newest known-good release that isn't the current
+// commit, restoring its kept release directory when it still exists and
+// rebuilding it otherwise.
+func (p *Pipeline) Rollback(ctx context.Context, state *app.AppState) error {
+	target := state.RollbackTarget()
+	if target == nil {
+		return fmt.Errorf("no known-good release to roll back to")
+	}
+	return p.rollbackTo(ctx, state, *target)
+}
+
+func (p *Pipeline) rollbackTo(ctx context.Context, state *app.AppState, target app.Release) error {
+	if restored, err := p.restoreRelease(ctx, state, target); restored {
+		return err
+	}
+	from := state.CommitSHA
+	state.CommitSHA = target.CommitSHA
+	return p.run(ctx, state, true, from)
+}
+
+func (p *Pipeline) run(ctx context.Context, state *app.AppState, isRedeploy bool, rollbackFrom string) error {
 	// Set up log streaming — batch lines and send to callback URL
 	var logStreamer *logBatcher
 	if state.CallbackURL != "" {
 		logsURL := strings.TrimSuffix(state.CallbackURL, "/status") + "/logs"
-		logStreamer = newLogBatcher(state.AppID, logsURL, p.cfg.API.APIKey, 5*time.Second)
+		logStreamer = newLogBatcher(state.AppID, logsURL, p.cfg.API.APIKey, p.cfg.Auth.RequestSigningKey, 5*time.Second)
 		logStreamer.start()
 	}
 
+	kind := app.BuildDeploy
+	if rollbackFrom != "" {
+		kind = app.BuildRollback
+	} else if isRedeploy {
+		kind = app.BuildRedeploy
+	}
+	buildLog := StartBuildLog(p.cfg, p.store, state, kind)
+	defer buildLog.Close()
+
 	logger := logging.NewDeployLogger(state.AppID, func(appID, line string) {
-		p.store.AppendLog(appID, line)
+		buildLog.WriteLine(line)
 		p.hub.Publish(appID, line)
 		if logStreamer != nil {
 			logStreamer.add(line)
 		}
 	})
 
-	isRedeploy := len(redeploy) > 0 && redeploy[0]
-
 	sctx := &StepContext{
-		AppState:  state,
-		Config:    p.cfg,
-		Logger:    logger,
-		Ports:     p.ports,
-		Store:     p.store,
-		Caddy:     p.caddy,
-		EnvMap:    make(map[string]string),
-		Processes: make(map[string]string),
-		Redeploy:  isRedeploy,
+		AppState:     state,
+		Config:       p.cfg,
+		Logger:       logger,
+		Ports:        p.ports,
+		Store:        p.store,
+		Caddy:        p.caddy,
+		Events:       p.events,
+		EnvMap:       make(map[string]string),
+		Processes:    make(map[string]string),
+		Redeploy:     isRedeploy,
+		RollbackFrom: rollbackFrom,
 	}
 
-	if isRedeploy {
+	if rollbackFrom != "" {
+		logger.Log("starting rollback pipeline for %s (%s → %s)", state.AppID, shortSHA(rollbackFrom), shortSHA(state.CommitSHA))
+	} else if isRedeploy {
 		logger.Log("starting redeploy pipeline for %s", state.AppID)
 	} else {
 		logger.Log("starting deploy pipeline for %s", state.AppID)
@@ -88,34 +130,77 @@ func (p *Pipeline) Run(ctx context.Context, state *app.AppState, redeploy ...boo
 			logger.Log("step %s failed: %v", step.Name(), err)
 			_ = p.store.UpdateStatus(state.AppID, app.StatusFailed, err.Error())
 
+			// A failed blue/green redeploy leaves the old version serving
+			failedSHA := state.CommitSHA
+			aborted := abortBlueGreen(sctx)
+			if aborted {
+				_ = p.store.UpdateStatus(state.AppID, app.StatusRunning, "redeploy failed: "+err.Error())
+			}
+
 			// Run on_failure hooks (best-effort, don't fail on hook errors)
 			if sctx.ReviewConfig != nil {
 				logger.Log("running on_failure hooks")
-				if hookErr := RunHooksFromConfig(sctx.ReviewConfig, HookOnFailure, sctx.RepoDir, state.RubyVersion, buildEnvSlice(sctx.EnvMap)); hookErr != nil {
+				out := logger.Writer(string(HookOnFailure))
+				hookErr := RunHooksFromConfig(sctx.ReviewConfig, HookOnFailure, sctx.RepoDir, state.RubyVersion, buildEnvSlice(sctx.EnvMap), out)
+				out.Close()
+				if hookErr != nil {
 					logger.Log("on_failure hook error (non-fatal): %v", hookErr)
 				}
 			}
 
+			// The old release (if any) is still current and untouched
+			discardRelease(sctx)
+
 			// Send failure callback to web app
 			if state.CallbackURL != "" {
 				logger.Log("sending failure callback to %s", state.CallbackURL)
-				cb := callback.NewClient(p.cfg.API.APIKey)
+				cb := callback.NewClient(p.cfg.API.APIKey, p.cfg.Auth.RequestSigningKey)
 				cb.SendStatus(state.CallbackURL, callback.StatusPayload{
-					AppID:  state.AppID,
-					Status: string(app.StatusFailed),
-					Error:  err.Error(),
+					AppID:        state.AppID,
+					Status:       string(app.StatusFailed),
+					Error:        err.Error(),
+					CommitSHA:    failedSHA,
+					RollbackFrom: rollbackFrom,
 				})
 			}
 
+			stepErr := fmt.Errorf("step %s: %w", step.Name(), err)
+
+			// Roll a failed redeploy back to the last known-good release,
+			// unless blue/green already left the old version serving.
+			// Never roll back a rollback.
+			if target := state.RollbackTarget(); target != nil && isRedeploy && rollbackFrom == "" && !aborted && p.cfg.Deploy.AutoRollback {
+				logger.Log("rolling back to last known-good release %s", shortSHA(target.CommitSHA))
+				if logStreamer != nil {
+					logStreamer.stop()
+				}
+				if rbErr := p.rollbackTo(ctx, state, *target); rbErr != nil {
+					return fmt.Errorf("%w (rollback failed: %v)", stepErr, rbErr)
+				}
+				return fmt.Errorf("%w (rolled back to %s)", stepErr, shortSHA(target.CommitSHA))
+			}
+
 			if logStreamer != nil {
 				logStreamer.stop()
 			}
 			p.hub.Close(state.AppID)
-			return fmt.Errorf("step %s: %w", step.Name(), err)
+			return stepErr
 		}
 	}
 
-	logger.Log("deploy pipeline complete for %s", state.AppID)
+	// Remember this release so a later failed redeploy can roll back to it
+	_ = p.store.RecordRelease(state.AppID, app.Release{
+		ID:              sctx.ReleaseID,
+		CommitSHA:       state.CommitSHA,
+		ProcessCommands: state.ProcessCommands,
+		DeployedAt:      time.Now(),
+	}, p.cfg.Deploy.KeepReleases)
+
+	if rollbackFrom != "" {
+		logger.Log("rollback complete for %s: running %s", state.AppID, shortSHA(state.CommitSHA))
+	} else {
+		logger.Log("deploy pipeline complete for %s", state.AppID)
+	}
 
 	// Flush any remaining log lines
 	if logStreamer != nil {
@@ -126,6 +211,34 @@ func (p *Pipeline) Run(ctx context.Context, state *app.AppState, redeploy ...boo
 	return nil
 }
 
+// StartBuildLog creates the on-disk log for a deploy or teardown and records
+// it on the app, deleting logs of builds beyond keep_builds. Returns nil
+// (which discards lines) if the file can't be created.
+func StartBuildLog(cfg *config.Config, store *app.Store, state *app.AppState, kind string) *logging.BuildLog {
+	buildLog, err := logging.CreateBuildLog(cfg.Paths.LogDir, state.AppID)
+	if err != nil {
+		log.Printf("deploy: build log for %s: %v", state.AppID, err)
+		return nil
+	}
+	dropped, _ := store.AddBuild(state.AppID, app.Build{
+		ID:        buildLog.ID,
+		Kind:      kind,
+		StartedAt: time.Now(),
+	}, cfg.Logs.KeepBuilds)
+	for _, b := range dropped {
+		_ = logging.RemoveBuildLog(cfg.Paths.LogDir, state.AppID, b.ID)
+	}
+	return buildLog
+}
+
+// shortSHA abbreviates a commit SHA for log lines.
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
+
 // logBatcher collects log lines and sends them in batches to the callback logs URL.
 type logBatcher struct {
 	appID   string
@@ -137,11 +250,11 @@ type logBatcher struct {
 	ticker  *time.Ticker
 }
 
-func newLogBatcher(appID, logsURL, apiKey string, interval time.Duration) *logBatcher {
+func newLogBatcher(appID, logsURL, apiKey, signingKey string, interval time.Duration) *logBatcher {
 	return &logBatcher{
 		appID:   appID,
 		logsURL: logsURL,
-		client:  callback.NewClient(apiKey),
+		client:  callback.NewClient(apiKey, signingKey),
 		done:    make(chan struct{}),
 		ticker:  time.NewTicker(interval),
 	}
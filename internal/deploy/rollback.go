@@ -0,0 +1,98 @@
+package deploy
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/logging"
+)
+
+// restoreRelease rolls back without rebuilding: `current` is pointed back at
+// the kept release directory and the processes the release ran are
+// restarted from it. It returns false, having changed nothing, when the
+// release can't be restored that way (its directory was pruned, or it was
+// recorded before releases kept their process commands), so the caller
+// rebuilds it instead.
+func (p *Pipeline) restoreRelease(ctx context.Context, state *app.AppState, target app.Release) (bool, error) {
+	if p.sup == nil || target.ID == "" || len(target.ProcessCommands) == 0 {
+		return false, nil
+	}
+	if _, err := os.Stat(filepath.Join(state.AppDir, "releases", target.ID)); err != nil {
+		return false, nil
+	}
+
+	var logStreamer *logBatcher
+	if state.CallbackURL != "" {
+		logsURL := strings.TrimSuffix(state.CallbackURL, "/status") + "/logs"
+		logStreamer = newLogBatcher(state.AppID, logsURL, p.cfg.API.APIKey, p.cfg.Auth.RequestSigningKey, 5*time.Second)
+		logStreamer.start()
+		defer logStreamer.stop()
+	}
+	buildLog := StartBuildLog(p.cfg, p.store, state, app.BuildRollback)
+	defer buildLog.Close()
+	defer p.hub.Close(state.AppID)
+
+	logger := logging.NewDeployLogger(state.AppID, func(appID, line string) {
+		buildLog.WriteLine(line)
+		p.hub.Publish(appID, line)
+		if logStreamer != nil {
+			logStreamer.add(line)
+		}
+	})
+
+	from := state.CommitSHA
+	logger.Log("restoring release %s for %s (%s → %s) without rebuilding", target.ID, state.AppID, shortSHA(from), shortSHA(target.CommitSHA))
+
+	fail := func(err error) (bool, error) {
+		logger.Log("rollback failed: %v", err)
+		_ = p.store.UpdateStatus(state.AppID, app.StatusFailed, err.Error())
+		return true, err
+	}
+
+	_ = p.store.UpdateStatus(state.AppID, app.StatusStarting, "")
+	if _, err := switchCurrent(state.AppDir, target.ID); err != nil {
+		return fail(err)
+	}
+	logger.Log("current → releases/%s", target.ID)
+
+	state.CommitSHA = target.CommitSHA
+	state.ProcessCommands = target.ProcessCommands
+	logger.Log("restarting processes")
+	if err := p.sup.RestartAll(state); err != nil {
+		return fail(err)
+	}
+
+	sctx := &StepContext{
+		AppState:     state,
+		Config:       p.cfg,
+		Logger:       logger,
+		Ports:        p.ports,
+		Store:        p.store,
+		Caddy:        p.caddy,
+		Events:       p.events,
+		AppDir:       state.AppDir,
+		Port:         state.Port,
+		RollbackFrom: from,
+	}
+	for _, step := range []Step{&HealthCheckStep{}, &CallbackStep{}} {
+		select {
+		case <-ctx.Done():
+			return fail(fmt.Errorf("rollback cancelled"))
+		default:
+		}
+		logger.Log("step: %s", step.Name())
+		if err := step.Run(sctx); err != nil {
+			return fail(fmt.Errorf("step %s: %w", step.Name(), err))
+		}
+	}
+
+	target.DeployedAt = time.Now()
+	_ = p.store.RecordRelease(state.AppID, target, p.cfg.Deploy.KeepReleases)
+	logger.Log("rollback complete for %s: running %s", state.AppID, shortSHA(state.CommitSHA))
+	return true, nil
+}
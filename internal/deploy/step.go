@@ -5,6 +5,7 @@ import (
 	"github.com/reviewapps-dev/rad/internal/caddy"
 	"github.com/reviewapps-dev/rad/internal/config"
 	"github.com/reviewapps-dev/rad/internal/logging"
+	"github.com/reviewapps-dev/rad/internal/logstream"
 	"github.com/reviewapps-dev/rad/internal/port"
 	"github.com/reviewapps-dev/rad/internal/reviewappsyml"
 )
@@ -21,10 +22,14 @@ type StepContext struct {
 	Ports     *port.Allocator
 	Store     *app.Store
 	Caddy     *caddy.Manager
+	Events    *logstream.Events // lifecycle events for dashboards; may be nil
 
 	// Enriched during pipeline
 	AppDir       string
-	RepoDir      string
+	MirrorDir    string // AppDir/repo: the git checkout releases are created from
+	ReleaseID    string // Directory name under AppDir/releases
+	ReleaseDir   string
+	RepoDir      string // Where the app is built and run from (ReleaseDir + app_path)
 	RubyPath     string
 	NodePath     string
 	ReviewConfig *reviewappsyml.Config
@@ -41,4 +46,25 @@ type StepContext struct {
 
 	// Redeploy mode — update in place instead of fresh deploy
 	Redeploy bool
+
+	// Set when redeploying a known-good release: the commit being rolled back
+	// from. AppState.CommitSHA holds the commit being rolled back to.
+	RollbackFrom string
+
+	// Blue/green redeploy: the new web process boots on Port while the old
+	// one keeps serving on PreviousPort until CutoverStep swaps them.
+	BlueGreen         bool
+	NewWeb            app.ProcessInfo
+	PreviousPort      int
+	PreviousProcesses map[string]app.ProcessInfo
+
+	// What status reports for the old version, put back on abort: the
+	// commit and commands are overwritten before the new one is healthy.
+	PreviousCommitSHA       string
+	PreviousProcessCommands map[string]string
+
+	// The release `current` pointed at before ActivateReleaseStep, and its
+	// app_path, so an aborted blue/green redeploy can point it back.
+	PreviousRelease string
+	PreviousAppPath string
 }
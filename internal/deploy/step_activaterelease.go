@@ -0,0 +1,136 @@
+package deploy
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/reviewapps-dev/rad/internal/git"
+)
+
+// ActivateReleaseStep points AppDir/current at the new release once it has
+// passed its health check, then prunes releases beyond deploy.keep_releases.
+type ActivateReleaseStep struct{}
+
+func (s *ActivateReleaseStep) Name() string { return "activate-release" }
+
+func (s *ActivateReleaseStep) Run(ctx *StepContext) error {
+	if ctx.ReleaseDir == "" {
+		return nil
+	}
+
+	previous, err := switchCurrent(ctx.AppDir, ctx.ReleaseID)
+	if err != nil {
+		return err
+	}
+	if previous != "" {
+		ctx.PreviousRelease = filepath.Base(previous)
+	}
+	ctx.PreviousAppPath = ctx.AppState.AppPath
+
+	appPath, err := filepath.Rel(ctx.ReleaseDir, ctx.RepoDir)
+	if err != nil {
+		appPath = ""
+	}
+	ctx.AppState.AppPath = appPath
+	ctx.Logger.Log("current → releases/%s", ctx.ReleaseID)
+
+	// The previous release keeps serving until cutover, so it's kept too
+	pruneReleases(ctx, ctx.Config.Deploy.KeepReleases, filepath.Base(previous))
+	return nil
+}
+
+// switchCurrent points appDir/current at releases/releaseID and returns
+// the release it pointed at before. The symlink is swapped with a rename so
+// `current` never goes missing.
+func switchCurrent(appDir, releaseID string) (string, error) {
+	current := filepath.Join(appDir, "current")
+	previous, _ := os.Readlink(current)
+	tmp := current + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(filepath.Join("releases", releaseID), tmp); err != nil {
+		return "", fmt.Errorf("symlink release: %w", err)
+	}
+	if err := os.Rename(tmp, current); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("activate release: %w", err)
+	}
+	return previous, nil
+}
+
+// pruneReleases removes all but the newest keep release directories. The
+// active release and previous are never removed. Best-effort: errors are
+// only logged.
+func pruneReleases(ctx *StepContext, keep int, previous string) {
+	if keep <= 0 {
+		return
+	}
+
+	releasesDir := filepath.Join(ctx.AppDir, "releases")
+	entries, err := os.ReadDir(releasesDir)
+	if err != nil {
+		return
+	}
+
+	// Release IDs start with a UTC timestamp, so names sort oldest first
+	var ids []string
+	for _, e := range entries {
+		if e.IsDir() {
+			ids = append(ids, e.Name())
+		}
+	}
+	sort.Strings(ids)
+	if len(ids) <= keep {
+		return
+	}
+
+	for _, id := range ids[:len(ids)-keep] {
+		if id == ctx.ReleaseID || id == previous {
+			continue
+		}
+		ctx.Logger.Log("removing old release %s", id)
+		if err := os.RemoveAll(filepath.Join(releasesDir, id)); err != nil {
+			ctx.Logger.Log("remove release %s: %v", id, err)
+		}
+	}
+	if err := git.PruneWorktrees(ctx.MirrorDir); err != nil {
+		ctx.Logger.Log("%v", err)
+	}
+}
+
+// deactivateRelease points `current` back at the release that was active
+// before this deploy, if the deploy already switched it.
+func deactivateRelease(ctx *StepContext) {
+	if ctx.ReleaseDir == "" || ctx.PreviousRelease == "" {
+		return
+	}
+	current, _ := os.Readlink(filepath.Join(ctx.AppDir, "current"))
+	if filepath.Base(current) != ctx.ReleaseID {
+		return
+	}
+	if _, err := switchCurrent(ctx.AppDir, ctx.PreviousRelease); err != nil {
+		ctx.Logger.Log("restore current: %v", err)
+		return
+	}
+	ctx.AppState.AppPath = ctx.PreviousAppPath
+	ctx.Logger.Log("current → releases/%s", ctx.PreviousRelease)
+}
+
+// discardRelease removes the release directory of a failed deploy, unless
+// it's already the active one.
+func discardRelease(ctx *StepContext) {
+	if ctx.ReleaseDir == "" {
+		return
+	}
+	current, _ := os.Readlink(filepath.Join(ctx.AppDir, "current"))
+	if filepath.Base(current) == ctx.ReleaseID {
+		return
+	}
+	ctx.Logger.Log("removing failed release %s", ctx.ReleaseID)
+	if err := os.RemoveAll(ctx.ReleaseDir); err != nil {
+		ctx.Logger.Log("remove release %s: %v", ctx.ReleaseID, err)
+		return
+	}
+	_ = git.PruneWorktrees(ctx.MirrorDir)
+}
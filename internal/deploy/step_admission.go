@@ -0,0 +1,21 @@
+package deploy
+
+import (
+	"github.com/reviewapps-dev/rad/internal/admission"
+)
+
+// AdmissionStep re-checks host limits when the deploy actually starts. The
+// API checks them when the deploy is requested, but other deploys may have
+// used up the room while this one sat in the queue. Redeploys are only
+// checked by the API: failing one here would mark a healthy running app
+// failed and stop its supervision for nothing.
+type AdmissionStep struct{}
+
+func (s *AdmissionStep) Name() string { return "admission" }
+
+func (s *AdmissionStep) Run(ctx *StepContext) error {
+	if ctx.Redeploy {
+		return nil
+	}
+	return admission.New(ctx.Config, ctx.Store).Check(ctx.AppState.AppID)
+}
@@ -1,15 +1,42 @@
 package deploy
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/reviewapps-dev/rad/internal/process"
+)
 
 type AllocatePortStep struct{}
 
 func (s *AllocatePortStep) Name() string { return "allocate-port" }
 
 func (s *AllocatePortStep) Run(ctx *StepContext) error {
-	// On redeploy, reuse the existing port if we have one
+	// On redeploy, reuse the app's port. The state is the source of truth:
+	// the allocator can hold more than one port for the app (e.g. around a
+	// blue/green swap) and doesn't say which one is serving.
 	if ctx.Redeploy {
-		if existingPort, ok := ctx.Ports.GetPort(ctx.AppState.AppID); ok {
+		if existingPort := ctx.AppState.Port; existingPort > 0 {
+			ctx.Ports.Reserve(ctx.AppState.AppID, existingPort)
+			// With Caddy in front and the old web process still up, boot the
+			// new version on a second port and swap once it's healthy
+			if ctx.Caddy != nil && ctx.Caddy.Enabled && process.IsAlive(ctx.AppState.Processes["web"].PID) {
+				newPort, err := ctx.Ports.AllocateAdditional(ctx.AppState.AppID)
+				if err != nil {
+					return fmt.Errorf("port allocation: %w", err)
+				}
+				ctx.BlueGreen = true
+				ctx.PreviousPort = existingPort
+				ctx.PreviousProcesses = ctx.AppState.Processes
+				ctx.PreviousProcessCommands = ctx.AppState.ProcessCommands
+				if rel := ctx.AppState.CurrentRelease(); rel != nil {
+					ctx.PreviousCommitSHA = rel.CommitSHA
+				}
+				ctx.Port = newPort
+				ctx.EnvMap["PORT"] = fmt.Sprintf("%d", newPort)
+				ctx.Logger.Log("redeploy: blue/green — new web on port %d, old keeps serving on %d", newPort, existingPort)
+				return nil
+			}
+
 			ctx.Port = existingPort
 			ctx.AppState.Port = existingPort
 			ctx.EnvMap["PORT"] = fmt.Sprintf("%d", existingPort)
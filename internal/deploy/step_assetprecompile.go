@@ -2,7 +2,6 @@ package deploy
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/reviewapps-dev/rad/internal/rv"
 )
@@ -20,10 +19,7 @@ func (s *AssetPrecompileStep) Run(ctx *StepContext) error {
 	ctx.Logger.Log("running: %s", buildCmd)
 
 	cmd := rv.ExecInDir(ctx.RepoDir, ctx.AppState.RubyVersion, buildEnvSlice(ctx.EnvMap), buildCmd)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
+	if err := runLogged(ctx, cmd, "assets"); err != nil {
 		return fmt.Errorf("asset precompile: %w", err)
 	}
 
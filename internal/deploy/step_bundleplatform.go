@@ -2,7 +2,6 @@ package deploy
 
 import (
 	"fmt"
-	"os"
 	"os/exec"
 	"runtime"
 	"strings"
@@ -21,10 +20,7 @@ func (s *BundlePlatformStep) Run(ctx *StepContext) error {
 	// Use -S so ruby searches PATH for the bundle script
 	cmd := rv.RunInDir(ctx.RepoDir, ctx.AppState.RubyVersion, nil,
 		"-S", "bundle", "lock", "--add-platform", platform)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
+	if err := runLogged(ctx, cmd, "bundle"); err != nil {
 		return fmt.Errorf("bundle lock --add-platform %s: %w", platform, err)
 	}
 
@@ -1,6 +1,8 @@
 package deploy
 
 import (
+	"fmt"
+
 	"github.com/reviewapps-dev/rad/internal/caddy"
 )
 
@@ -34,6 +36,10 @@ func (s *CaddyConfigStep) Run(ctx *StepContext) error {
 
 	// Reload Caddy to pick up the new config
 	if err := ctx.Caddy.Reload(); err != nil {
+		if ctx.BlueGreen {
+			// The old process is stopped next, so traffic has to move now
+			return fmt.Errorf("caddy reload: %w", err)
+		}
 		ctx.Logger.Log("caddy reload failed (non-fatal): %v", err)
 		// Non-fatal — the app is still accessible via localhost:{port}
 	} else {
@@ -16,7 +16,7 @@ func (s *CallbackStep) Run(ctx *StepContext) error {
 		return nil
 	}
 
-	client := callback.NewClient(ctx.Config.API.APIKey)
+	client := callback.NewClient(ctx.Config.API.APIKey, ctx.Config.Auth.RequestSigningKey)
 
 	url := fmt.Sprintf("http://localhost:%d", ctx.Port)
 	if ctx.AppState.Subdomain != "" {
@@ -30,6 +30,9 @@ func (s *CallbackStep) Run(ctx *StepContext) error {
 		URL:       url,
 		CommitSHA: ctx.AppState.CommitSHA,
 	}
+	if ctx.RollbackFrom != "" {
+		payload.RollbackFrom = ctx.RollbackFrom
+	}
 
 	ctx.Logger.Log("sending callback to %s", ctx.AppState.CallbackURL)
 	return client.SendStatus(ctx.AppState.CallbackURL, payload)
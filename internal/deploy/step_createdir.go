@@ -12,12 +12,13 @@ func (s *CreateDirStep) Name() string { return "create-dir" }
 func (s *CreateDirStep) Run(ctx *StepContext) error {
 	appDir := filepath.Join(ctx.Config.Paths.AppsDir, ctx.AppState.AppID)
 	ctx.AppDir = appDir
-	ctx.RepoDir = filepath.Join(appDir, "repo")
+	ctx.MirrorDir = filepath.Join(appDir, "repo")
+	ctx.RepoDir = ctx.MirrorDir
 	ctx.AppState.AppDir = appDir
 
 	if ctx.Redeploy {
 		ctx.Logger.Log("redeploy: reusing app directory %s", appDir)
-		return nil
+		return os.MkdirAll(filepath.Join(appDir, "releases"), 0755)
 	}
 
 	ctx.Logger.Log("creating app directory: %s", appDir)
@@ -30,5 +31,8 @@ func (s *CreateDirStep) Run(ctx *StepContext) error {
 		}
 	}
 
-	return os.MkdirAll(ctx.RepoDir, 0755)
+	if err := os.MkdirAll(filepath.Join(appDir, "releases"), 0755); err != nil {
+		return err
+	}
+	return os.MkdirAll(ctx.MirrorDir, 0755)
 }
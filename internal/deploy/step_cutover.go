@@ -0,0 +1,113 @@
+package deploy
+
+import (
+	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/caddy"
+	"github.com/reviewapps-dev/rad/internal/process"
+)
+
+// CutoverStep finishes a blue/green redeploy. By the time it runs the new
+// web process has passed its health check and Caddy points at it, so the old
+// processes can be stopped and the remaining new ones started.
+type CutoverStep struct{}
+
+func (s *CutoverStep) Name() string { return "cutover" }
+
+func (s *CutoverStep) Run(ctx *StepContext) error {
+	if !ctx.BlueGreen {
+		return nil
+	}
+
+	appID := ctx.AppState.AppID
+
+	// Point the store at the new web process before stopping the old ones,
+	// so the crash monitor never sees the old PIDs die and revives them
+	_ = ctx.Store.ClearProcesses(appID)
+	recordProcess(ctx, ctx.NewWeb)
+	ctx.AppState.Port = ctx.Port
+	_ = ctx.Store.SetPort(appID, ctx.Port)
+
+	ctx.Logger.Log("stopping %d old process(es)", len(ctx.PreviousProcesses))
+	for name, proc := range ctx.PreviousProcesses {
+		if proc.PID > 0 {
+			ctx.Logger.Log("stopping old process %q (pid=%d)", name, proc.PID)
+			if err := process.Stop(proc.PID); err != nil {
+				ctx.Logger.Log("failed to stop old process %q (pid=%d): %v", name, proc.PID, err)
+			}
+		}
+	}
+
+	ctx.Ports.ReleasePort(ctx.PreviousPort)
+	ctx.Logger.Log("released old port %d", ctx.PreviousPort)
+	ctx.BlueGreen = false
+
+	// Start everything except web, which is already up
+	procs := ctx.Processes
+	instances := make(map[string]string)
+	for procType, cmd := range procs {
+		if procType == "web" {
+			continue
+		}
+		for _, name := range app.InstanceNames(procType, ctx.AppState.ScaleFor(procType)) {
+			instances[name] = cmd
+		}
+	}
+	if err := startInstances(ctx, instances, sortProcessNames(instances)); err != nil {
+		return err
+	}
+
+	if len(procs) > 0 {
+		ctx.AppState.ProcessCommands = procs
+	}
+
+	ctx.Logger.Log("cutover complete: serving from port %d", ctx.Port)
+	return nil
+}
+
+// abortBlueGreen undoes a blue/green redeploy that failed before cutover:
+// the new web process is stopped, its port released, Caddy pointed back at
+// the old process and `current` back at the old release, so restarts keep
+// running the old code. The state's commit and process commands are put
+// back too. Returns true if the old version is still serving.
+func abortBlueGreen(ctx *StepContext) bool {
+	if !ctx.BlueGreen {
+		return false
+	}
+
+	if ctx.PID > 0 {
+		ctx.Logger.Log("stopping new web process (pid=%d)", ctx.PID)
+		process.Stop(ctx.PID)
+	}
+	ctx.Ports.ReleasePort(ctx.Port)
+
+	if ctx.Caddy != nil && ctx.Caddy.Enabled {
+		subdomain := ctx.AppState.Subdomain
+		if subdomain == "" {
+			subdomain = ctx.AppState.AppID
+		}
+		cfg := caddy.SiteConfig{
+			AppID:     ctx.AppState.AppID,
+			Subdomain: subdomain,
+			Port:      ctx.PreviousPort,
+			LogDir:    ctx.Config.Paths.LogDir,
+		}
+		if err := ctx.Caddy.WriteSiteConfig(cfg); err != nil {
+			ctx.Logger.Log("restore caddy config: %v", err)
+		} else if err := ctx.Caddy.Reload(); err != nil {
+			ctx.Logger.Log("caddy reload failed: %v", err)
+		}
+	}
+
+	deactivateRelease(ctx)
+	if ctx.PreviousCommitSHA != "" {
+		ctx.AppState.CommitSHA = ctx.PreviousCommitSHA
+	}
+	ctx.AppState.ProcessCommands = ctx.PreviousProcessCommands
+
+	ctx.Port = ctx.PreviousPort
+	ctx.PID = ctx.AppState.PID
+	ctx.BlueGreen = false
+
+	ctx.Logger.Log("old version still serving on port %d", ctx.PreviousPort)
+	return true
+}
@@ -2,7 +2,6 @@ package deploy
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/reviewapps-dev/rad/internal/rv"
 )
@@ -18,10 +17,7 @@ func (s *DBPrepareStep) Run(ctx *StepContext) error {
 		ctx.Logger.Log("running setup command: %s", setupCmd)
 
 		cmd := rv.ExecInDir(ctx.RepoDir, ctx.AppState.RubyVersion, buildEnvSlice(ctx.EnvMap), setupCmd)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		if err := cmd.Run(); err != nil {
+		if err := runLogged(ctx, cmd, "setup"); err != nil {
 			return fmt.Errorf("setup command %q: %w", setupCmd, err)
 		}
 
@@ -38,10 +34,7 @@ func (s *DBPrepareStep) Run(ctx *StepContext) error {
 
 	cmd := rv.RunInDir(ctx.RepoDir, ctx.AppState.RubyVersion, buildEnvSlice(ctx.EnvMap),
 		"bin/rails", task)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
+	if err := runLogged(ctx, cmd, task); err != nil {
 		return fmt.Errorf("%s: %w", task, err)
 	}
 
@@ -74,6 +74,21 @@ func (s *DetectConfigStep) Run(ctx *StepContext) error {
 			}
 			ctx.Logger.Log("processes from reviewapps.yml: %v", names)
 		}
+
+		// Instance counts per process type; runtime overrides are kept separately
+		ctx.AppState.Scale = nil
+		for name, count := range cfg.Scale {
+			if err := app.ValidateScale(name, count); err != nil {
+				return fmt.Errorf("reviewapps.yml: %w", err)
+			}
+			if ctx.AppState.Scale == nil {
+				ctx.AppState.Scale = make(map[string]int)
+			}
+			ctx.AppState.Scale[name] = count
+		}
+		if len(ctx.AppState.Scale) > 0 {
+			ctx.Logger.Log("scale from reviewapps.yml: %v", ctx.AppState.Scale)
+		}
 	} else {
 		ctx.Logger.Log("no reviewapps.yml found, using defaults")
 	}
@@ -1,10 +1,20 @@
 package deploy
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
 	"github.com/reviewapps-dev/rad/internal/app"
 	"github.com/reviewapps-dev/rad/internal/git"
 )
 
+// GitCloneStep brings AppDir/repo up to date and checks the commit out into a
+// fresh release directory, which the rest of the pipeline builds in. The
+// release only becomes `current` once it passes its health check.
 type GitCloneStep struct{}
 
 func (s *GitCloneStep) Name() string { return "git-clone" }
@@ -12,24 +22,46 @@ func (s *GitCloneStep) Name() string { return "git-clone" }
 func (s *GitCloneStep) Run(ctx *StepContext) error {
 	_ = ctx.Store.UpdateStatus(ctx.AppState.AppID, app.StatusCloning, "")
 
-	if ctx.Redeploy {
+	if ctx.RollbackFrom != "" {
+		ctx.Logger.Log("rollback: resetting to %s", ctx.AppState.CommitSHA)
+		if err := git.FetchAndResetTo(ctx.MirrorDir, ctx.AppState.CommitSHA); err != nil {
+			return err
+		}
+	} else if ctx.Redeploy {
 		ctx.Logger.Log("fetching updates for %s (branch: %s)", ctx.AppState.RepoURL, ctx.AppState.Branch)
-		if err := git.FetchAndReset(ctx.RepoDir, ctx.AppState.Branch); err != nil {
+		if err := git.FetchAndReset(ctx.MirrorDir, ctx.AppState.Branch); err != nil {
 			return err
 		}
 	} else {
-		ctx.Logger.Log("cloning %s (branch: %s) into %s", ctx.AppState.RepoURL, ctx.AppState.Branch, ctx.RepoDir)
-		if err := git.Clone(ctx.AppState.RepoURL, ctx.AppState.Branch, ctx.RepoDir); err != nil {
+		ctx.Logger.Log("cloning %s (branch: %s) into %s", ctx.AppState.RepoURL, ctx.AppState.Branch, ctx.MirrorDir)
+		if err := git.Clone(ctx.AppState.RepoURL, ctx.AppState.Branch, ctx.MirrorDir); err != nil {
 			return err
 		}
 	}
 
 	// Get actual commit SHA
-	sha, err := git.GetCommitSHA(ctx.RepoDir)
-	if err == nil {
-		ctx.AppState.CommitSHA = sha
-		ctx.Logger.Log("commit: %s", sha)
+	sha, err := git.GetCommitSHA(ctx.MirrorDir)
+	if err != nil {
+		return fmt.Errorf("read commit: %w", err)
+	}
+	ctx.AppState.CommitSHA = sha
+	ctx.Logger.Log("commit: %s", sha)
+
+	// The random suffix keeps two deploys of one commit in the same second
+	// (a retry, a webhook fired twice) from colliding
+	suffix := make([]byte, 2)
+	rand.Read(suffix)
+	ctx.ReleaseID = time.Now().UTC().Format("20060102150405") + "-" + shortSHA(sha) + "-" + hex.EncodeToString(suffix)
+	ctx.ReleaseDir = filepath.Join(ctx.AppDir, "releases", ctx.ReleaseID)
+	if _, err := os.Stat(ctx.ReleaseDir); err == nil {
+		return fmt.Errorf("release %s already exists", ctx.ReleaseID)
+	}
+
+	ctx.Logger.Log("creating release %s", ctx.ReleaseID)
+	if err := git.AddWorktree(ctx.MirrorDir, ctx.ReleaseDir, sha); err != nil {
+		return err
 	}
+	ctx.RepoDir = ctx.ReleaseDir
 
 	// Init submodules (best-effort)
 	if err := git.InitSubmodules(ctx.RepoDir); err != nil {
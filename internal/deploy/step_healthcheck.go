@@ -1,10 +1,12 @@
 package deploy
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/reviewapps-dev/rad/internal/app"
 	"github.com/reviewapps-dev/rad/internal/health"
+	"github.com/reviewapps-dev/rad/internal/logstream"
 )
 
 type HealthCheckStep struct{}
@@ -40,6 +42,14 @@ func (s *HealthCheckStep) Run(ctx *StepContext) error {
 	ctx.Logger.Log("waiting for health check (timeout=%s, interval=%s)", timeout, interval)
 
 	if err := health.Check(ctx.Port, host, timeout, interval, customPath); err != nil {
+		if ctx.Events != nil {
+			ctx.Events.Publish(logstream.Event{
+				AppID:   ctx.AppState.AppID,
+				Type:    logstream.EventHealth,
+				Process: "web",
+				Message: fmt.Sprintf("health check failed during deploy: %v", err),
+			})
+		}
 		return err
 	}
 
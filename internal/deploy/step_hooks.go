@@ -2,7 +2,7 @@ package deploy
 
 import (
 	"fmt"
-	"os"
+	"io"
 
 	"github.com/reviewapps-dev/rad/internal/reviewappsyml"
 	"github.com/reviewapps-dev/rad/internal/rv"
@@ -42,10 +42,7 @@ func (s *RunHooksStep) Run(ctx *StepContext) error {
 		ctx.Logger.Log("  [%d/%d] %s", i+1, len(hooks), hook)
 
 		cmd := rv.ExecInDir(ctx.RepoDir, ctx.AppState.RubyVersion, buildEnvSlice(ctx.EnvMap), hook)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		if err := cmd.Run(); err != nil {
+		if err := runLogged(ctx, cmd, string(s.Phase)); err != nil {
 			return fmt.Errorf("hook %q failed: %w", hook, err)
 		}
 	}
@@ -85,8 +82,9 @@ func hooksForPhase(cfg *reviewappsyml.Config, phase HookPhase) []string {
 }
 
 // RunHooksFromConfig runs hooks for a phase outside of the pipeline (e.g. teardown, failure).
-// This is a standalone function that doesn't need a StepContext.
-func RunHooksFromConfig(cfg *reviewappsyml.Config, phase HookPhase, repoDir, rubyVersion string, env []string) error {
+// This is a standalone function that doesn't need a StepContext. Hook output
+// is written to out.
+func RunHooksFromConfig(cfg *reviewappsyml.Config, phase HookPhase, repoDir, rubyVersion string, env []string, out io.Writer) error {
 	hooks := hooksForPhase(cfg, phase)
 	if len(hooks) == 0 {
 		return nil
@@ -94,8 +92,8 @@ func RunHooksFromConfig(cfg *reviewappsyml.Config, phase HookPhase, repoDir, rub
 
 	for _, hook := range hooks {
 		cmd := rv.ExecInDir(repoDir, rubyVersion, env, hook)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
+		cmd.Stdout = out
+		cmd.Stderr = out
 		if err := cmd.Run(); err != nil {
 			return fmt.Errorf("hook %q failed: %w", hook, err)
 		}
@@ -1,6 +1,8 @@
 package deploy
 
 import (
+	"os/exec"
+
 	"github.com/reviewapps-dev/rad/internal/rv"
 )
 
@@ -12,7 +14,10 @@ func (s *InstallGemsStep) Run(ctx *StepContext) error {
 	ctx.Logger.Log("installing gems via rv clean-install")
 
 	env := buildEnvSlice(ctx.EnvMap)
-	if err := rv.CleanInstall(ctx.RepoDir, env); err != nil {
+	out := ctx.Logger.Writer("gems")
+	err := rv.CleanInstall(ctx.RepoDir, env, out)
+	out.Close()
+	if err != nil {
 		return err
 	}
 
@@ -20,6 +25,16 @@ func (s *InstallGemsStep) Run(ctx *StepContext) error {
 	return nil
 }
 
+// runLogged runs cmd with its stdout and stderr streamed into the build log,
+// each line tagged with source.
+func runLogged(ctx *StepContext, cmd *exec.Cmd, source string) error {
+	out := ctx.Logger.Writer(source)
+	defer out.Close()
+	cmd.Stdout = out
+	cmd.Stderr = out
+	return cmd.Run()
+}
+
 func buildEnvSlice(m map[string]string) []string {
 	result := make([]string, 0, len(m))
 	for k, v := range m {
@@ -2,7 +2,6 @@ package deploy
 
 import (
 	"fmt"
-	"os"
 	"os/exec"
 	"strings"
 
@@ -41,9 +40,7 @@ func (s *InstallJSDepsStep) Run(ctx *StepContext) error {
 		return fmt.Errorf("unknown JS package manager: %s", ctx.JSPackageManager)
 	}
 
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := runLogged(ctx, cmd, ctx.JSPackageManager); err != nil {
 		return fmt.Errorf("%s install: %w", ctx.JSPackageManager, err)
 	}
 
@@ -19,7 +19,10 @@ func (s *InstallNodeStep) Run(ctx *StepContext) error {
 
 	ctx.Logger.Log("installing node %s via fnm", version)
 
-	if err := fnm.Install(version); err != nil {
+	out := ctx.Logger.Writer("fnm")
+	err := fnm.Install(version, out)
+	out.Close()
+	if err != nil {
 		return err
 	}
 
@@ -22,7 +22,10 @@ func (s *InstallRubyStep) Run(ctx *StepContext) error {
 
 	ctx.Logger.Log("installing ruby %s via rv", version)
 
-	if err := rv.Install(version); err != nil {
+	out := ctx.Logger.Writer("rv")
+	err := rv.Install(version, out)
+	out.Close()
+	if err != nil {
 		return err
 	}
 
@@ -2,7 +2,6 @@ package deploy
 
 import (
 	"fmt"
-	"os"
 
 	"github.com/reviewapps-dev/rad/internal/rv"
 )
@@ -26,10 +25,7 @@ func (s *SeedStep) Run(ctx *StepContext) error {
 	ctx.Logger.Log("running seed: %s", seedCmd)
 
 	cmd := rv.ExecInDir(ctx.RepoDir, ctx.AppState.RubyVersion, buildEnvSlice(ctx.EnvMap), seedCmd)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
+	if err := runLogged(ctx, cmd, "seed"); err != nil {
 		return fmt.Errorf("seed: %w", err)
 	}
 
@@ -2,12 +2,13 @@ package deploy
 
 import (
 	"fmt"
-	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/logwriter"
 	"github.com/reviewapps-dev/rad/internal/process"
 	"github.com/reviewapps-dev/rad/internal/rv"
 )
@@ -27,6 +28,31 @@ func (s *StartProcessesStep) Run(ctx *StepContext) error {
 		}
 	}
 
+	// Expand each process type into its scaled instances (worker → worker.1, worker.2)
+	instances := make(map[string]string)
+	for procType, cmd := range procs {
+		for _, name := range app.InstanceNames(procType, ctx.AppState.ScaleFor(procType)) {
+			instances[name] = cmd
+		}
+	}
+
+	// Blue/green: only boot the new web process for now. The old processes
+	// keep serving until CutoverStep swaps them after the health check.
+	if ctx.BlueGreen {
+		cmd, ok := instances["web"]
+		if !ok {
+			return fmt.Errorf("blue/green redeploy needs a web process")
+		}
+		proc, err := startProcess(ctx, "web", cmd)
+		if err != nil {
+			return err
+		}
+		ctx.PID = proc.PID
+		ctx.NewWeb = proc
+		ctx.Logger.Log("new web process started next to the old one (pid=%d, port=%d)", proc.PID, ctx.Port)
+		return nil
+	}
+
 	// On redeploy, stop ALL old processes first
 	if ctx.Redeploy && len(ctx.AppState.Processes) > 0 {
 		ctx.Logger.Log("stopping %d old process(es)", len(ctx.AppState.Processes))
@@ -41,61 +67,74 @@ func (s *StartProcessesStep) Run(ctx *StepContext) error {
 	_ = ctx.Store.ClearProcesses(ctx.AppState.AppID)
 
 	// Start web process first, then others in sorted order
-	sortedNames := sortProcessNames(procs)
-
-	for _, name := range sortedNames {
-		cmd := procs[name]
-
-		// Expand $PORT in the command for the web process
-		if name == "web" {
-			cmd = strings.ReplaceAll(cmd, "$PORT", fmt.Sprintf("%d", ctx.Port))
-		}
-
-		ctx.Logger.Log("starting process %q: %s", name, cmd)
+	if err := startInstances(ctx, instances, sortProcessNames(instances)); err != nil {
+		return err
+	}
 
-		logPath := processLogPath(ctx, name)
-		logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if err != nil {
-			return fmt.Errorf("open log file for %s: %w", name, err)
-		}
+	// Save the process commands so restart can re-use them
+	ctx.AppState.ProcessCommands = procs
 
-		execCmd := rv.ExecInDir(ctx.RepoDir, ctx.AppState.RubyVersion, buildEnvSlice(ctx.EnvMap), cmd)
-		execCmd.Stdout = logFile
-		execCmd.Stderr = logFile
+	ctx.Logger.Log("all %d process(es) started", len(instances))
+	return nil
+}
 
-		info, err := process.Start(execCmd)
+// startInstances starts the named instances and records them in the store.
+// If one fails, everything started so far is stopped again.
+func startInstances(ctx *StepContext, instances map[string]string, names []string) error {
+	for _, name := range names {
+		proc, err := startProcess(ctx, name, instances[name])
 		if err != nil {
-			logFile.Close()
-			// If a non-web process fails to start, stop everything we already started
-			ctx.Logger.Log("process %q failed to start: %v", name, err)
 			stopAllProcesses(ctx)
-			return fmt.Errorf("start process %s: %w", name, err)
+			return err
 		}
+		recordProcess(ctx, proc)
+	}
+	return nil
+}
 
-		proc := app.ProcessInfo{
-			Name: name,
-			PID:  info.PID,
-		}
-		if name == "web" {
-			proc.Port = ctx.Port
-			ctx.PID = info.PID
-			ctx.AppState.PID = info.PID
-		}
+// startProcess starts a single process instance with its output going to
+// the process log through a rotating log relay. It does not record the process in the store.
+func startProcess(ctx *StepContext, name, cmd string) (app.ProcessInfo, error) {
+	// Expand $PORT in the command for the web process
+	if name == "web" {
+		cmd = strings.ReplaceAll(cmd, "$PORT", fmt.Sprintf("%d", ctx.Port))
+	}
 
-		_ = ctx.Store.SetProcess(ctx.AppState.AppID, proc)
-		if ctx.AppState.Processes == nil {
-			ctx.AppState.Processes = make(map[string]app.ProcessInfo)
-		}
-		ctx.AppState.Processes[name] = proc
+	ctx.Logger.Log("starting process %q: %s", name, cmd)
 
-		ctx.Logger.Log("process %q started (pid=%d, log=%s)", name, info.PID, logPath)
+	logPath := processLogPath(ctx, name)
+	execCmd := rv.ExecInDir(ctx.RepoDir, ctx.AppState.RubyVersion, buildEnvSlice(ctx.EnvMap), cmd)
+	info, err := logwriter.Start(execCmd, logPath, name, logwriter.OptionsFromConfig(ctx.Config.Logs))
+	if err != nil {
+		ctx.Logger.Log("process %q failed to start: %v", name, err)
+		return app.ProcessInfo{}, fmt.Errorf("start process %s: %w", name, err)
 	}
 
-	// Save the process commands so restart can re-use them
-	ctx.AppState.ProcessCommands = procs
+	proc := app.ProcessInfo{
+		Name:      name,
+		PID:       info.PID,
+		StartedAt: time.Now(),
+	}
+	if name == "web" {
+		proc.Port = ctx.Port
+	}
 
-	ctx.Logger.Log("all %d process(es) started", len(procs))
-	return nil
+	ctx.Logger.Log("process %q started (pid=%d, log=%s)", name, info.PID, logPath)
+	return proc, nil
+}
+
+// recordProcess saves a started process in the store and the step context.
+func recordProcess(ctx *StepContext, proc app.ProcessInfo) {
+	if proc.Name == "web" {
+		ctx.PID = proc.PID
+		ctx.AppState.PID = proc.PID
+	}
+
+	_ = ctx.Store.SetProcess(ctx.AppState.AppID, proc)
+	if ctx.AppState.Processes == nil {
+		ctx.AppState.Processes = make(map[string]app.ProcessInfo)
+	}
+	ctx.AppState.Processes[proc.Name] = proc
 }
 
 // sortProcessNames returns process names with "web" first, then the rest alphabetically.
@@ -0,0 +1,307 @@
+package diskusage
+
+import (
+	"encoding/json"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/config"
+	"github.com/reviewapps-dev/rad/internal/git"
+)
+
+// Cleaner periodically frees disk space that apps no longer need: old files
+// in tmp/cache, precompiled assets no longer in the asset manifest, release
+// directories that aren't current or remembered for rollback, and log files
+// of apps that are gone.
+type Cleaner struct {
+	cfg      *config.Config
+	store    *app.Store
+	interval time.Duration
+	done     chan struct{}
+}
+
+func NewCleaner(cfg *config.Config, store *app.Store, interval time.Duration) *Cleaner {
+	return &Cleaner{
+		cfg:      cfg,
+		store:    store,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+func (c *Cleaner) Start() {
+	go c.loop()
+	log.Printf("cleanup: started (interval=%s)", c.interval)
+}
+
+func (c *Cleaner) Stop() {
+	close(c.done)
+	log.Printf("cleanup: stopped")
+}
+
+func (c *Cleaner) loop() {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			c.Run()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Run does one cleanup pass and returns the number of bytes freed.
+func (c *Cleaner) Run() int64 {
+	maxAge := time.Duration(c.cfg.Cleanup.MaxAgeHours) * time.Hour
+	var freed int64
+
+	for _, state := range c.store.List() {
+		if state.AppDir == "" {
+			continue
+		}
+		// Only touch apps that aren't mid-deploy or teardown
+		switch state.Status {
+		case app.StatusRunning, app.StatusSleeping, app.StatusStopped, app.StatusFailed:
+		default:
+			continue
+		}
+
+		freed += c.pruneReleases(state)
+		freed += removeOlderThan(filepath.Join(state.RepoDir(), "tmp", "cache"), maxAge)
+		freed += pruneAssets(filepath.Join(state.RepoDir(), "public", "assets"), maxAge)
+	}
+
+	freed += c.pruneOrphanLogs(maxAge)
+
+	if freed > 0 {
+		log.Printf("cleanup: freed %d MB", freed/1024/1024)
+	}
+	return freed
+}
+
+// pruneReleases removes release directories that are neither current nor
+// one of the known-good releases kept for rollback, e.g. left over from a
+// deploy interrupted by a rad restart. Directories created since `current`
+// was last switched are left alone: they may belong to a deploy that's
+// between its health check and switching `current`, which the app's status
+// doesn't show.
+func (c *Cleaner) pruneReleases(state *app.AppState) int64 {
+	releasesDir := filepath.Join(state.AppDir, "releases")
+	entries, err := os.ReadDir(releasesDir)
+	if err != nil {
+		return 0
+	}
+
+	currentLink := filepath.Join(state.AppDir, "current")
+	link, err := os.Lstat(currentLink)
+	if err != nil {
+		return 0
+	}
+	switched := link.ModTime()
+
+	keep := make(map[string]bool)
+	if current, err := os.Readlink(currentLink); err == nil {
+		keep[filepath.Base(current)] = true
+	}
+	for _, rel := range state.Releases {
+		if rel.ID != "" {
+			keep[rel.ID] = true
+		}
+	}
+
+	var freed int64
+	for _, e := range entries {
+		if !e.IsDir() || keep[e.Name()] {
+			continue
+		}
+		if info, err := e.Info(); err != nil || !info.ModTime().Before(switched) {
+			continue
+		}
+		dir := filepath.Join(releasesDir, e.Name())
+		size := DirSize(dir)
+		log.Printf("cleanup: removing stale release %s/%s", state.AppID, e.Name())
+		if err := os.RemoveAll(dir); err != nil {
+			log.Printf("cleanup: remove %s: %v", dir, err)
+			continue
+		}
+		freed += size
+	}
+	if freed > 0 {
+		_ = git.PruneWorktrees(filepath.Join(state.AppDir, "repo"))
+	}
+	return freed
+}
+
+// pruneAssets removes precompiled assets that no manifest references and
+// that are older than maxAge. Sprockets keeps old digests around across
+// deploys into the same checkout. Does nothing without a manifest.
+func pruneAssets(assetsDir string, maxAge time.Duration) int64 {
+	referenced, ok := manifestFiles(assetsDir)
+	if !ok {
+		return 0
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	var freed int64
+	filepath.WalkDir(assetsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasPrefix(d.Name(), ".") {
+			return nil
+		}
+		rel, _ := filepath.Rel(assetsDir, path)
+		rel = filepath.ToSlash(rel)
+		// Compressed variants (.gz, .br) go with their asset
+		base := strings.TrimSuffix(strings.TrimSuffix(rel, ".gz"), ".br")
+		if referenced[rel] || referenced[base] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			return nil
+		}
+		if os.Remove(path) == nil {
+			freed += info.Size()
+		}
+		return nil
+	})
+	return freed
+}
+
+// manifestFiles returns the digested file names listed in the Sprockets
+// (.sprockets-manifest-*.json) and Propshaft (.manifest.json) manifests.
+func manifestFiles(assetsDir string) (map[string]bool, bool) {
+	files := make(map[string]bool)
+	found := false
+
+	sprockets, _ := filepath.Glob(filepath.Join(assetsDir, ".sprockets-manifest-*.json"))
+	for _, path := range sprockets {
+		var m struct {
+			Files map[string]json.RawMessage `json:"files"`
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || json.Unmarshal(data, &m) != nil {
+			continue
+		}
+		found = true
+		for name := range m.Files {
+			files[name] = true
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(assetsDir, ".manifest.json")); err == nil {
+		// Propshaft maps logical paths to a digested path, or (1.x) to an
+		// object with a digested_path
+		var m map[string]json.RawMessage
+		if json.Unmarshal(data, &m) == nil {
+			found = true
+			for _, v := range m {
+				var digested string
+				if json.Unmarshal(v, &digested) != nil {
+					var entry struct {
+						DigestedPath string `json:"digested_path"`
+					}
+					if json.Unmarshal(v, &entry) != nil {
+						continue
+					}
+					digested = entry.DigestedPath
+				}
+				files[digested] = true
+			}
+		}
+	}
+
+	return files, found
+}
+
+// pruneOrphanLogs removes log files and build log directories in LogDir that
+// belong to no known app and haven't been written to for maxAge.
+func (c *Cleaner) pruneOrphanLogs(maxAge time.Duration) int64 {
+	entries, err := os.ReadDir(c.cfg.Paths.LogDir)
+	if err != nil {
+		return 0
+	}
+
+	apps := c.store.List()
+	cutoff := time.Now().Add(-maxAge)
+	var freed int64
+	for _, e := range entries {
+		name := e.Name()
+		path := filepath.Join(c.cfg.Paths.LogDir, name)
+		if path == filepath.Clean(c.cfg.Paths.AuditLog) {
+			continue
+		}
+		if e.IsDir() {
+			// Only app log directories, i.e. ones holding build logs
+			if _, err := os.Stat(filepath.Join(path, "builds")); err != nil {
+				continue
+			}
+		} else if !strings.Contains(name, ".log") {
+			continue
+		}
+		owned := false
+		for _, state := range apps {
+			if name == state.AppID || strings.HasPrefix(name, state.AppID+".") {
+				owned = true
+				break
+			}
+		}
+		if owned {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		size := info.Size()
+		if e.IsDir() {
+			size = DirSize(path)
+		}
+		log.Printf("cleanup: removing orphaned log %s", name)
+		if os.RemoveAll(path) == nil {
+			freed += size
+		}
+	}
+	return freed
+}
+
+// removeOlderThan removes regular files under dir not modified for maxAge.
+func removeOlderThan(dir string, maxAge time.Duration) int64 {
+	cutoff := time.Now().Add(-maxAge)
+	var freed int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() || info.ModTime().After(cutoff) {
+			return nil
+		}
+		if os.Remove(path) == nil {
+			freed += info.Size()
+		}
+		return nil
+	})
+	return freed
+}
+
+// DirSize returns the total size of the regular files under dir.
+func DirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,138 @@
+package diskusage
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/config"
+)
+
+// Categories that disk usage is broken down into.
+const (
+	CategoryRepo        = "repo"         // AppDir/repo, the git checkout releases are made from
+	CategoryCode        = "code"         // release checkouts, minus everything below
+	CategoryGems        = "gems"         // vendor/bundle
+	CategoryNodeModules = "node_modules" // node_modules
+	CategoryAssets      = "assets"       // precompiled assets under public/
+	CategoryTmp         = "tmp"          // tmp/ (caches, pids)
+	CategorySQLite      = "sqlite"       // *.sqlite3 databases in AppDir
+	CategoryLogs        = "logs"         // process, access and build logs in LogDir, plus log/ in releases
+	CategoryOther       = "other"        // .env and anything else in AppDir
+)
+
+// Usage is disk usage in bytes by category.
+type Usage struct {
+	Total      int64            `json:"total_bytes"`
+	Categories map[string]int64 `json:"categories"`
+}
+
+func newUsage() Usage {
+	return Usage{Categories: make(map[string]int64)}
+}
+
+func (u *Usage) add(category string, n int64) {
+	u.Categories[category] += n
+	u.Total += n
+}
+
+// Merge adds other's totals into u.
+func (u *Usage) Merge(other Usage) {
+	for category, n := range other.Categories {
+		u.add(category, n)
+	}
+}
+
+// ForApp walks an app's directory, its log files and build logs and totals their size
+// by category. Symlinks (like `current`) are not followed, so nothing is
+// counted twice.
+func ForApp(cfg *config.Config, state *app.AppState) Usage {
+	u := newUsage()
+
+	if state.AppDir != "" {
+		filepath.WalkDir(state.AppDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+			rel, _ := filepath.Rel(state.AppDir, path)
+			u.add(classify(rel), info.Size())
+			return nil
+		})
+	}
+
+	for _, f := range LogFiles(cfg, state.AppID) {
+		if info, err := os.Stat(f); err == nil {
+			u.add(CategoryLogs, info.Size())
+		}
+	}
+	u.add(CategoryLogs, DirSize(filepath.Join(cfg.Paths.LogDir, state.AppID)))
+
+	return u
+}
+
+// LogFiles returns the files in LogDir that belong to an app: {app_id}.log,
+// {app_id}.*.log and {app_id}.access.log, plus their rotated segments. App
+// IDs can't contain dots (app.ValidateID), so another app's logs never match.
+func LogFiles(cfg *config.Config, appID string) []string {
+	var files []string
+	for _, pattern := range []string{appID + ".log", appID + ".log.*", appID + ".*.log", appID + ".*.log.*"} {
+		matches, _ := filepath.Glob(filepath.Join(cfg.Paths.LogDir, pattern))
+		files = append(files, matches...)
+	}
+	return files
+}
+
+// classify maps a path relative to AppDir to a category.
+func classify(rel string) string {
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+
+	switch parts[0] {
+	case "repo":
+		// Apps deployed before release directories build in repo/ itself
+		if c := classifyRelease(parts[1:]); c != CategoryCode {
+			return c
+		}
+		return CategoryRepo
+	case "releases":
+		// releases/<id>/... → classify within the checkout
+		if len(parts) > 2 {
+			return classifyRelease(parts[2:])
+		}
+		return CategoryCode
+	}
+
+	if strings.Contains(parts[len(parts)-1], ".sqlite3") {
+		return CategorySQLite
+	}
+	return CategoryOther
+}
+
+// classifyRelease maps path components inside a release checkout to a
+// category. Matches anywhere in the path so monorepo app_paths are covered.
+func classifyRelease(parts []string) string {
+	for i, p := range parts {
+		next := ""
+		if i+1 < len(parts) {
+			next = parts[i+1]
+		}
+		switch {
+		case p == "node_modules":
+			return CategoryNodeModules
+		case p == "vendor" && next == "bundle":
+			return CategoryGems
+		case p == "public" && (next == "assets" || next == "packs" || next == "vite"):
+			return CategoryAssets
+		case p == "tmp":
+			return CategoryTmp
+		case p == "log":
+			return CategoryLogs
+		}
+	}
+	return CategoryCode
+}
@@ -0,0 +1,374 @@
+package execjob
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os/exec"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Job statuses.
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"    // exited non-zero or couldn't be waited on
+	StatusKilled    = "killed"    // via Kill (DELETE) or teardown
+	StatusTimedOut  = "timed_out" // ran past its timeout
+)
+
+const (
+	// maxLines is how many output lines a job keeps, in a ring indexed by
+	// line number. Older lines are overwritten and readers that fall behind
+	// skip ahead.
+	maxLines = 10000
+	// keepFinished is how many finished jobs are remembered per app.
+	keepFinished = 20
+)
+
+// ErrTooManyJobs is returned by Start when the app or the host already has
+// as many running jobs as allowed.
+var ErrTooManyJobs = errors.New("too many running exec jobs")
+
+// Line is one line of job output.
+type Line struct {
+	Stream string `json:"stream"` // stdout or stderr
+	Text   string `json:"text"`
+}
+
+// Info is a snapshot of a job.
+type Info struct {
+	ID         string     `json:"id"`
+	AppID      string     `json:"app_id"`
+	Command    string     `json:"command"`
+	Status     string     `json:"status"`
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// Job is a command running in the background with its output kept in
+// memory. Lines are numbered from 0 so readers can resume where they left
+// off.
+type Job struct {
+	ID        string
+	AppID     string
+	Command   string
+	StartedAt time.Time
+
+	cmd  *exec.Cmd
+	done chan struct{}
+
+	mu         sync.Mutex
+	ring       []Line        // line n is at ring[n%maxLines]; grows to maxLines
+	next       int           // number of the next line
+	changed    chan struct{} // closed and replaced whenever lines or status change
+	status     string
+	exitCode   *int
+	finishedAt time.Time
+	stopReason string // killed or timed_out, once a stop was asked for
+}
+
+// Manager runs and remembers exec jobs. Jobs live only as long as the rad
+// process.
+type Manager struct {
+	maxPerApp int
+	max       int
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewManager returns a Manager that runs at most maxPerApp jobs per app and
+// max jobs in all at once. Zero means no limit.
+func NewManager(maxPerApp, max int) *Manager {
+	return &Manager{
+		maxPerApp: maxPerApp,
+		max:       max,
+		jobs:      make(map[string]*Job),
+	}
+}
+
+// Start runs cmd in its own process group as a job for appID. A positive
+// timeout stops the job once it runs that long. It returns ErrTooManyJobs
+// when starting the job would go over the Manager's limits.
+func (m *Manager) Start(appID, command string, cmd *exec.Cmd, timeout time.Duration) (*Job, error) {
+	j := &Job{
+		ID:        newID(),
+		AppID:     appID,
+		Command:   command,
+		StartedAt: time.Now(),
+		cmd:       cmd,
+		done:      make(chan struct{}),
+		changed:   make(chan struct{}),
+		status:    StatusRunning,
+	}
+	stdout := &lineWriter{job: j, stream: "stdout"}
+	stderr := &lineWriter{job: j, stream: "stderr"}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Use process group so we can kill the whole tree
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// Don't wait forever on pipes held open by orphaned grandchildren
+	cmd.WaitDelay = 5 * time.Second
+
+	// Check the limits and start under the lock so concurrent requests
+	// can't both take the last slot
+	m.mu.Lock()
+	if err := m.checkLimits(appID); err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("start job: %w", err)
+	}
+	m.jobs[j.ID] = j
+	m.prune(appID)
+	m.mu.Unlock()
+
+	go func() {
+		err := cmd.Wait()
+		stdout.flush()
+		stderr.flush()
+		j.finish(err)
+		log.Printf("exec: job %s for %s finished (%s)", j.ID, appID, j.Info().Status)
+	}()
+
+	if timeout > 0 {
+		go func() {
+			select {
+			case <-j.done:
+			case <-time.After(timeout):
+				log.Printf("exec: job %s for %s timed out after %s", j.ID, appID, timeout)
+				j.stop(StatusTimedOut)
+			}
+		}()
+	}
+	return j, nil
+}
+
+// Get returns the job with the given ID if it belongs to appID.
+func (m *Manager) Get(appID, id string) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok || j.AppID != appID {
+		return nil, false
+	}
+	return j, true
+}
+
+// List returns the app's jobs, newest first.
+func (m *Manager) List(appID string) []*Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.appJobs(appID)
+}
+
+// KillApp stops the app's running jobs and forgets all of its jobs.
+func (m *Manager) KillApp(appID string) {
+	m.mu.Lock()
+	jobs := m.appJobs(appID)
+	for _, j := range jobs {
+		delete(m.jobs, j.ID)
+	}
+	m.mu.Unlock()
+
+	for _, j := range jobs {
+		j.Kill()
+	}
+}
+
+// checkLimits returns ErrTooManyJobs if the app or the host is already
+// running as many jobs as allowed. Callers hold m.mu.
+func (m *Manager) checkLimits(appID string) error {
+	running, appRunning := 0, 0
+	for _, j := range m.jobs {
+		select {
+		case <-j.done:
+			continue
+		default:
+		}
+		running++
+		if j.AppID == appID {
+			appRunning++
+		}
+	}
+	if m.maxPerApp > 0 && appRunning >= m.maxPerApp {
+		return fmt.Errorf("%w: %s already has %d", ErrTooManyJobs, appID, appRunning)
+	}
+	if m.max > 0 && running >= m.max {
+		return fmt.Errorf("%w: the host already has %d", ErrTooManyJobs, running)
+	}
+	return nil
+}
+
+// appJobs returns the app's jobs, newest first. Callers hold m.mu.
+func (m *Manager) appJobs(appID string) []*Job {
+	var jobs []*Job
+	for _, j := range m.jobs {
+		if j.AppID == appID {
+			jobs = append(jobs, j)
+		}
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].StartedAt.After(jobs[b].StartedAt) })
+	return jobs
+}
+
+// prune forgets the app's oldest finished jobs beyond keepFinished. Callers
+// hold m.mu.
+func (m *Manager) prune(appID string) {
+	finished := 0
+	for _, j := range m.appJobs(appID) {
+		select {
+		case <-j.done:
+			finished++
+			if finished > keepFinished {
+				delete(m.jobs, j.ID)
+			}
+		default:
+		}
+	}
+}
+
+// Info returns a snapshot of the job.
+func (j *Job) Info() Info {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	info := Info{
+		ID:        j.ID,
+		AppID:     j.AppID,
+		Command:   j.Command,
+		Status:    j.status,
+		ExitCode:  j.exitCode,
+		StartedAt: j.StartedAt,
+	}
+	if !j.finishedAt.IsZero() {
+		t := j.finishedAt
+		info.FinishedAt = &t
+	}
+	return info
+}
+
+// Done is closed once the job has exited.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// Lines returns the output from line number from on, the number of the
+// line after them, and a channel that's closed when more output arrives or
+// the job finishes. Lines dropped from the start of the buffer are skipped.
+func (j *Job) Lines(from int) ([]Line, int, <-chan struct{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if first := j.next - len(j.ring); from < first {
+		from = first
+	}
+	if from > j.next {
+		from = j.next
+	}
+	out := make([]Line, 0, j.next-from)
+	for n := from; n < j.next; n++ {
+		out = append(out, j.ring[n%maxLines])
+	}
+	return out, j.next, j.changed
+}
+
+// Kill stops the job and everything it started, and waits for it to exit.
+func (j *Job) Kill() {
+	j.stop(StatusKilled)
+	<-j.done
+}
+
+// stop signals the job's process group with stopGroup: SIGTERM, SIGKILL if
+// it's still running after a grace period, then SIGKILL for anything left
+// in the group.
+func (j *Job) stop(reason string) {
+	j.mu.Lock()
+	if j.status != StatusRunning || j.stopReason != "" {
+		j.mu.Unlock()
+		return
+	}
+	j.stopReason = reason
+	j.mu.Unlock()
+
+	stopGroup(j.cmd.Process.Pid)
+}
+
+func (j *Job) append(stream, text string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	line := Line{Stream: stream, Text: text}
+	if len(j.ring) < maxLines {
+		j.ring = append(j.ring, line)
+	} else {
+		j.ring[j.next%maxLines] = line
+	}
+	j.next++
+	j.notify()
+}
+
+func (j *Job) finish(err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	code := exitCode(err)
+	j.exitCode = &code
+	j.finishedAt = time.Now()
+	switch {
+	case j.stopReason != "":
+		j.status = j.stopReason
+	case code == 0:
+		j.status = StatusSucceeded
+	default:
+		j.status = StatusFailed
+	}
+	j.notify()
+	close(j.done)
+}
+
+// notify wakes readers waiting in Lines. Callers hold j.mu.
+func (j *Job) notify() {
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+// lineWriter splits a stream into lines and appends them to the job.
+type lineWriter struct {
+	job     *Job
+	stream  string
+	partial []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.partial = append(w.partial, p...)
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+		w.job.append(w.stream, string(bytes.TrimSuffix(w.partial[:i], []byte("\r"))))
+		w.partial = w.partial[i+1:]
+	}
+	return len(p), nil
+}
+
+// flush appends a trailing line without a newline.
+func (w *lineWriter) flush() {
+	if len(w.partial) > 0 {
+		w.job.append(w.stream, string(w.partial))
+		w.partial = nil
+	}
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,123 @@
+package execjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"syscall"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/process"
+)
+
+// maxOutput is how much of each of stdout and stderr Run keeps. Beyond it
+// the start of the output is dropped.
+const maxOutput = 1 << 20
+
+// Result is the outcome of a command run with Run.
+type Result struct {
+	ExitCode        int    `json:"exit_code"` // -1 if killed by a signal
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	StdoutTruncated bool   `json:"stdout_truncated"`
+	StderrTruncated bool   `json:"stderr_truncated"`
+	DurationMS      int64  `json:"duration_ms"`
+	TimedOut        bool   `json:"timed_out"`
+}
+
+// Run runs cmd in its own process group and waits for it. When ctx ends
+// first the whole group is stopped with stopGroup, and
+// TimedOut is set if ctx hit its deadline. The error is only for commands
+// that couldn't be started.
+func Run(ctx context.Context, cmd *exec.Cmd) (*Result, error) {
+	stdout := &tailBuffer{limit: maxOutput}
+	stderr := &tailBuffer{limit: maxOutput}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// Use process group so we can kill the whole tree
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	// Don't wait forever on pipes held open by orphaned grandchildren
+	cmd.WaitDelay = 5 * time.Second
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start command: %w", err)
+	}
+	waited := make(chan error, 1)
+	go func() { waited <- cmd.Wait() }()
+
+	var err error
+	select {
+	case err = <-waited:
+	case <-ctx.Done():
+		stopGroup(cmd.Process.Pid)
+		err = <-waited
+	}
+
+	return &Result{
+		ExitCode:        exitCode(err),
+		Stdout:          stdout.String(),
+		Stderr:          stderr.String(),
+		StdoutTruncated: stdout.truncated,
+		StderrTruncated: stderr.truncated,
+		DurationMS:      time.Since(start).Milliseconds(),
+		TimedOut:        errors.Is(ctx.Err(), context.DeadlineExceeded),
+	}, nil
+}
+
+// stopGroup stops the process group led by pid the way process.Stop does,
+// then kills whatever is left of the group. process.Stop only escalates
+// while the leader is alive, so children that ignore SIGTERM would
+// otherwise outlive a leader that exits on it.
+func stopGroup(pid int) {
+	process.Stop(pid)
+	syscall.Kill(-pid, syscall.SIGKILL)
+}
+
+// exitCode returns the exit code from an exec.Cmd's Wait error: 0 for nil,
+// -1 when the process was killed by a signal or not waited on.
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// tailBuffer keeps the last limit bytes written to it. Once full it's a
+// ring: new bytes overwrite the oldest in place.
+type tailBuffer struct {
+	buf       []byte
+	start     int // offset of the oldest byte once buf is full
+	limit     int
+	truncated bool
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) > b.limit {
+		p = p[len(p)-b.limit:]
+		b.truncated = true
+	}
+	if room := b.limit - len(b.buf); room > 0 {
+		k := min(room, len(p))
+		b.buf = append(b.buf, p[:k]...)
+		p = p[k:]
+	}
+	for len(p) > 0 {
+		k := copy(b.buf[b.start:], p)
+		b.start = (b.start + k) % b.limit
+		p = p[k:]
+		b.truncated = true
+	}
+	return n, nil
+}
+
+// String returns the kept bytes, oldest first.
+func (b *tailBuffer) String() string {
+	return string(b.buf[b.start:]) + string(b.buf[:b.start])
+}
@@ -2,16 +2,18 @@ package fnm
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 )
 
-func Install(nodeVersion string) error {
+// Install installs a Node version, writing fnm's output to out.
+func Install(nodeVersion string, out io.Writer) error {
 	cmd := exec.Command("fnm", "install", nodeVersion)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = out
+	cmd.Stderr = out
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("fnm install %s: %w", nodeVersion, err)
 	}
@@ -43,6 +43,49 @@ func FetchAndReset(repoDir, branch string) error {
 	return nil
 }
 
+// FetchAndResetTo resets the working tree to a specific commit, fetching it
+// first if the shallow clone doesn't have it.
+func FetchAndResetTo(repoDir, sha string) error {
+	have := exec.Command("git", "cat-file", "-e", sha+"^{commit}")
+	have.Dir = repoDir
+	if err := have.Run(); err != nil {
+		fetch := exec.Command("git", "fetch", "--depth", "1", "origin", sha)
+		fetch.Dir = repoDir
+		if out, err := fetch.CombinedOutput(); err != nil {
+			return fmt.Errorf("git fetch %s: %w\n%s", sha, err, string(out))
+		}
+	}
+
+	reset := exec.Command("git", "reset", "--hard", sha)
+	reset.Dir = repoDir
+	if out, err := reset.CombinedOutput(); err != nil {
+		return fmt.Errorf("git reset: %w\n%s", err, string(out))
+	}
+
+	return nil
+}
+
+// AddWorktree checks out a commit into a new working tree at dest that shares
+// the object store of repoDir.
+func AddWorktree(repoDir, dest, sha string) error {
+	cmd := exec.Command("git", "worktree", "add", "--detach", dest, sha)
+	cmd.Dir = repoDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git worktree add: %w\n%s", err, string(out))
+	}
+	return nil
+}
+
+// PruneWorktrees drops bookkeeping for worktrees whose directories were removed.
+func PruneWorktrees(repoDir string) error {
+	cmd := exec.Command("git", "worktree", "prune")
+	cmd.Dir = repoDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("git worktree prune: %w\n%s", err, string(out))
+	}
+	return nil
+}
+
 func GetCommitSHA(repoDir string) (string, error) {
 	cmd := exec.Command("git", "rev-parse", "HEAD")
 	cmd.Dir = repoDir
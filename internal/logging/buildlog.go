@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/logwriter"
+)
+
+// BuildLog is the on-disk log of one deploy or teardown, at
+// {log_dir}/{app_id}/builds/{id}.log. A nil *BuildLog discards lines, so
+// callers can carry on if the file couldn't be created.
+type BuildLog struct {
+	ID string
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// BuildLogDir returns the directory holding an app's build logs.
+func BuildLogDir(logDir, appID string) string {
+	return filepath.Join(logDir, appID, "builds")
+}
+
+// BuildLogPath returns the path of one build log.
+func BuildLogPath(logDir, appID, id string) string {
+	return filepath.Join(BuildLogDir(logDir, appID), id+".log")
+}
+
+// NewBuildID returns a sortable, unique ID for a deploy: the UTC start time
+// plus a random suffix.
+func NewBuildID() string {
+	b := make([]byte, 3)
+	rand.Read(b)
+	return time.Now().UTC().Format("20060102T150405") + "-" + hex.EncodeToString(b)
+}
+
+// CreateBuildLog creates the log file for a new build.
+func CreateBuildLog(logDir, appID string) (*BuildLog, error) {
+	id := NewBuildID()
+	if err := os.MkdirAll(BuildLogDir(logDir, appID), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(BuildLogPath(logDir, appID, id), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &BuildLog{ID: id, f: f}, nil
+}
+
+// WriteLine appends one line to the log.
+func (b *BuildLog) WriteLine(line string) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.f.WriteString(line + "\n")
+}
+
+func (b *BuildLog) Close() error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.f.Close()
+}
+
+// ReadBuildLog returns the lines of a build log.
+func ReadBuildLog(logDir, appID, id string) ([]string, error) {
+	f, err := os.Open(BuildLogPath(logDir, appID, id))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return logwriter.ReadAllLines(f)
+}
+
+// RemoveBuildLog deletes a build log.
+func RemoveBuildLog(logDir, appID, id string) error {
+	err := os.Remove(BuildLogPath(logDir, appID, id))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -1,7 +1,9 @@
 package logging
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"sync"
 	"time"
@@ -22,7 +24,10 @@ func NewDeployLogger(appID string, onLine func(appID, line string)) *DeployLogge
 }
 
 func (l *DeployLogger) Log(format string, args ...any) {
-	line := fmt.Sprintf(format, args...)
+	l.emit(fmt.Sprintf(format, args...))
+}
+
+func (l *DeployLogger) emit(line string) {
 	ts := time.Now().Format("15:04:05")
 	full := fmt.Sprintf("[%s] %s", ts, line)
 
@@ -44,3 +49,54 @@ func (l *DeployLogger) Lines() []string {
 	copy(cp, l.lines)
 	return cp
 }
+
+// Writer returns an io.WriteCloser that logs each line written to it, for
+// use as a subprocess's Stdout and Stderr. Lines are tagged with [source]
+// unless source is empty. Close flushes a trailing line without a newline.
+func (l *DeployLogger) Writer(source string) io.WriteCloser {
+	return &lineWriter{logger: l, source: source}
+}
+
+type lineWriter struct {
+	logger *DeployLogger
+	source string
+	mu     sync.Mutex
+	buf    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.line(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *lineWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.buf) > 0 {
+		w.line(w.buf)
+		w.buf = nil
+	}
+	return nil
+}
+
+func (w *lineWriter) line(b []byte) {
+	// Progress bars redraw with \r; keep only the final state
+	if i := bytes.LastIndexByte(bytes.TrimRight(b, "\r"), '\r'); i >= 0 {
+		b = b[i+1:]
+	}
+	text := string(bytes.TrimRight(b, "\r"))
+	if w.source != "" {
+		text = "[" + w.source + "] " + text
+	}
+	w.logger.emit(text)
+}
@@ -0,0 +1,78 @@
+package logstream
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	EventStatus  = "status"  // app status changed; Message is the new status (and error)
+	EventCrash   = "crash"   // a process exited on its own
+	EventRestart = "restart" // a process was restarted
+	EventHealth  = "health"  // a health check failed
+)
+
+// Event is an app lifecycle event, for dashboards following an app live.
+type Event struct {
+	Time    time.Time `json:"ts"`
+	AppID   string    `json:"app_id"`
+	Type    string    `json:"type"`
+	Process string    `json:"process,omitempty"`
+	Message string    `json:"message"`
+}
+
+// Events is a pub/sub hub for app lifecycle events. Unlike Hub, it lives as
+// long as rad: subscriptions aren't closed when a deploy finishes. Slow
+// consumers have events dropped rather than blocking the publisher.
+type Events struct {
+	mu   sync.Mutex
+	subs map[string]map[chan Event]struct{}
+}
+
+func NewEvents() *Events {
+	return &Events{
+		subs: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// Subscribe returns a channel that receives events for the given app, and an
+// unsubscribe function. The channel is buffered (64 events).
+func (e *Events) Subscribe(appID string) (<-chan Event, func()) {
+	ch := make(chan Event, 64)
+	e.mu.Lock()
+	if e.subs[appID] == nil {
+		e.subs[appID] = make(map[chan Event]struct{})
+	}
+	e.subs[appID][ch] = struct{}{}
+	e.mu.Unlock()
+
+	unsub := func() {
+		e.mu.Lock()
+		delete(e.subs[appID], ch)
+		if len(e.subs[appID]) == 0 {
+			delete(e.subs, appID)
+		}
+		e.mu.Unlock()
+	}
+	return ch, unsub
+}
+
+// Publish sends an event to all subscribers for its app. A nil *Events
+// discards events.
+func (e *Events) Publish(ev Event) {
+	if e == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now()
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for ch := range e.subs[ev.AppID] {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package logstream
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/logwriter"
+)
+
+// watchedPollInterval is how often Follow checks the file anyway when
+// inotify is watching it.
+const watchedPollInterval = 5 * time.Second
+
+// Position is a point in a log that survives rotation: the inode of the file
+// (rotated segments keep it) and a byte offset into it. A zero Inode means
+// the file currently at the log's path.
+type Position struct {
+	Inode  uint64
+	Offset int64
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d-%d", p.Inode, p.Offset)
+}
+
+// ParsePosition parses a Position from its String form.
+func ParsePosition(s string) (Position, bool) {
+	var p Position
+	if _, err := fmt.Sscanf(s, "%d-%d", &p.Inode, &p.Offset); err != nil || p.Offset < 0 {
+		return Position{}, false
+	}
+	return p, true
+}
+
+// EndPosition returns the position at the end of the file at path.
+func EndPosition(path string) (Position, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{Inode: inode(info), Offset: info.Size()}, nil
+}
+
+// Follow calls fn with each complete line written to the log at path after
+// from, and the position just past it. It wakes on inotify events for the
+// file, falling back to polling every interval where inotify isn't
+// available. It follows the log across rotation (draining the old file
+// before moving to the next) and starts over if the file is truncated. It
+// returns once fn returns false or ctx is done, after a last read to the end
+// of the file.
+func Follow(ctx context.Context, path string, from Position, interval time.Duration, fn func(line string, pos Position) bool) error {
+	f, err := openAt(path, from)
+	if err != nil {
+		return err
+	}
+	defer func() { f.Close() }()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	pos := Position{Inode: inode(info), Offset: from.Offset}
+	r := bufio.NewReader(f)
+	var partial string
+
+	// read sends complete lines up to EOF. Returns false if fn asked to stop.
+	read := func() bool {
+		for {
+			chunk, err := r.ReadString('\n')
+			if err != nil {
+				partial += chunk
+				return true
+			}
+			pos.Offset += int64(len(partial) + len(chunk))
+			line := strings.TrimRight(partial+chunk, "\r\n")
+			partial = ""
+			if !fn(line, pos) {
+				return false
+			}
+		}
+	}
+
+	// With inotify, polling is only a safety net for missed events
+	var changed <-chan struct{}
+	if w, err := newWatcher(path); err == nil {
+		defer w.Close()
+		changed = w.C()
+		interval = watchedPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if !read() {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			read()
+			return nil
+		case <-changed:
+		case <-ticker.C:
+		}
+
+		cur, err := os.Stat(path)
+		if err != nil {
+			continue // mid-rotation; the new file shows up shortly
+		}
+
+		switch {
+		case inode(cur) != pos.Inode:
+			// Rotated: finish this file, including a last unterminated line
+			if !read() {
+				return nil
+			}
+			if partial != "" {
+				pos.Offset += int64(len(partial))
+				if !fn(partial, pos) {
+					return nil
+				}
+				partial = ""
+			}
+			next, err := os.Open(nextFile(path, pos.Inode))
+			if err != nil {
+				continue
+			}
+			nextInfo, err := next.Stat()
+			if err != nil {
+				next.Close()
+				continue
+			}
+			f.Close()
+			f = next
+			pos = Position{Inode: inode(nextInfo)}
+			r.Reset(f)
+		case cur.Size() < pos.Offset:
+			// Truncated in place: start over
+			if _, err := f.Seek(0, io.SeekStart); err == nil {
+				pos.Offset = 0
+				r.Reset(f)
+				partial = ""
+			}
+		}
+	}
+}
+
+// openAt opens the file from.Inode refers to (the current file or a rotated,
+// uncompressed segment) and seeks to from.Offset. If that file is gone, it
+// opens the current file at the start.
+func openAt(path string, from Position) (*os.File, error) {
+	name := path
+	offset := from.Offset
+	if from.Inode != 0 {
+		name = ""
+		for _, candidate := range append(logwriter.Segments(path), path) {
+			if info, err := os.Stat(candidate); err == nil && inode(info) == from.Inode {
+				name = candidate
+				break
+			}
+		}
+		if name == "" {
+			name, offset = path, 0
+		}
+	}
+
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// nextFile returns the file that follows the one with the given inode: the
+// next newer segment, or the current file.
+func nextFile(path string, ino uint64) string {
+	files := append(logwriter.Segments(path), path)
+	for i, candidate := range files[:len(files)-1] {
+		if info, err := os.Stat(candidate); err == nil && inode(info) == ino {
+			return files[i+1]
+		}
+	}
+	return path
+}
+
+func inode(info os.FileInfo) uint64 {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return st.Ino
+	}
+	return 0
+}
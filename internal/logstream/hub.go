@@ -1,63 +1,256 @@
 package logstream
 
-import "sync"
+import (
+	"fmt"
+	"sync"
+)
 
-// Hub is a pub/sub hub for build log lines during deploys.
-// Subscribers receive lines on a buffered channel; slow consumers
-// have lines dropped rather than blocking the deploy pipeline.
+// ringSize is how many recent lines the hub keeps per app for replay.
+const ringSize = 5000
+
+// Entry is one published line and its sequence number. Sequence numbers
+// start at 1 and increase by one per line for the app, across deploys, until
+// the app is removed.
+type Entry struct {
+	Seq  uint64 `json:"seq"`
+	Line string `json:"line"`
+}
+
+// Hub is a pub/sub hub for build log lines during deploys and teardowns.
+// It keeps the last ringSize lines per app so subscribers can resume from a
+// sequence number. Subscribers read at their own pace; one that falls more
+// than ringSize lines behind gets a "lines skipped: N" entry instead of the
+// lines it missed, and one resuming after a sequence number the hub hasn't
+// reached (a stale id from before a restart) gets a "log reset" entry and
+// the ring from its start. Publishing never blocks.
 type Hub struct {
 	mu   sync.Mutex
-	subs map[string]map[chan string]struct{}
+	apps map[string]*appLog
+}
+
+type appLog struct {
+	ring     []Entry
+	next     uint64 // sequence number of the next line
+	start    uint64 // last sequence number before the current build's first line
+	open     bool   // lines published since the last Close
+	closedAt uint64 // last sequence number before the latest Close
+	closes   uint64 // number of Close calls
+	subs     int    // running subscriber goroutines
+	removed  bool   // Remove was called; dropped from the hub once subs is 0
+	wake     chan struct{}
 }
 
 func NewHub() *Hub {
 	return &Hub{
-		subs: make(map[string]map[chan string]struct{}),
+		apps: make(map[string]*appLog),
 	}
 }
 
-// Subscribe returns a channel that receives build log lines for the given app,
-// and an unsubscribe function. The channel is buffered (64 lines).
-func (h *Hub) Subscribe(appID string) (<-chan string, func()) {
-	ch := make(chan string, 64)
-	h.mu.Lock()
-	if h.subs[appID] == nil {
-		h.subs[appID] = make(map[chan string]struct{})
+// app returns the log for an app, creating it. A removed log that's still
+// draining is replaced, so an app deployed again under the same ID starts
+// afresh. Must hold h.mu.
+func (h *Hub) app(appID string) *appLog {
+	a, ok := h.apps[appID]
+	if !ok || a.removed {
+		a = &appLog{
+			ring: make([]Entry, ringSize),
+			next: 1,
+			wake: make(chan struct{}),
+		}
+		h.apps[appID] = a
 	}
-	h.subs[appID][ch] = struct{}{}
-	h.mu.Unlock()
+	return a
+}
 
-	unsub := func() {
-		h.mu.Lock()
-		delete(h.subs[appID], ch)
-		if len(h.subs[appID]) == 0 {
-			delete(h.subs, appID)
-		}
-		h.mu.Unlock()
+// broadcast wakes all subscribers. Must hold h.mu.
+func (a *appLog) broadcast() {
+	close(a.wake)
+	a.wake = make(chan struct{})
+}
+
+// oldest returns the sequence number of the oldest line still in the ring.
+func (a *appLog) oldest() uint64 {
+	if last := a.next - 1; last > ringSize {
+		return last - ringSize + 1
 	}
-	return ch, unsub
+	return 1
 }
 
-// Publish sends a line to all subscribers for the given app.
-// Non-blocking: drops lines for slow consumers.
-func (h *Hub) Publish(appID, line string) {
+// since returns the entries after seq that are still in the ring, and how
+// many lines after seq have already been dropped from it.
+func (a *appLog) since(seq uint64) ([]Entry, uint64) {
+	last := a.next - 1
+	oldest := a.oldest()
+	var skipped uint64
+	if seq+1 < oldest {
+		skipped = oldest - seq - 1
+		seq = oldest - 1
+	}
+	var entries []Entry
+	for s := seq + 1; s <= last; s++ {
+		entries = append(entries, a.ring[s%ringSize])
+	}
+	return entries, skipped
+}
+
+// Publish records a line for the given app, wakes its subscribers and
+// returns the line's sequence number.
+func (h *Hub) Publish(appID, line string) uint64 {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	for ch := range h.subs[appID] {
-		select {
-		case ch <- line:
-		default:
-		}
+	a := h.app(appID)
+	if !a.open {
+		a.open = true
+		a.start = a.next - 1
 	}
+	seq := a.next
+	a.ring[seq%ringSize] = Entry{Seq: seq, Line: line}
+	a.next++
+	a.broadcast()
+	return seq
 }
 
-// Close closes all subscriber channels for the given app,
-// signaling that the deploy has finished.
+// Close marks the end of a deploy or teardown. Subscribers from Subscribe
+// end once they've received every line published before it.
 func (h *Hub) Close(appID string) {
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	for ch := range h.subs[appID] {
-		close(ch)
+	a := h.app(appID)
+	a.open = false
+	a.closedAt = a.next - 1
+	a.closes++
+	a.broadcast()
+}
+
+// Remove forgets the app's lines once its subscribers have drained: each
+// ends after receiving what was published before Remove. Call it after the
+// app's final Close, when the app is gone for good.
+func (h *Hub) Remove(appID string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	a, ok := h.apps[appID]
+	if !ok {
+		return
+	}
+	a.removed = true
+	a.broadcast()
+	if a.subs == 0 {
+		delete(h.apps, appID)
+	}
+}
+
+// Last returns the sequence number of the latest line for the app.
+func (h *Hub) Last(appID string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.app(appID).next - 1
+}
+
+// BuildStart returns the sequence number just before the first line of the
+// deploy or teardown in progress. Between builds it's Last, so a subscriber
+// gets the next build from its first line.
+func (h *Hub) BuildStart(appID string) uint64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	a := h.app(appID)
+	if a.open {
+		return a.start
 	}
-	delete(h.subs, appID)
+	return a.next - 1
+}
+
+// Subscribe returns a channel that receives the app's lines after sequence
+// number after, and an unsubscribe function. The channel is closed once the
+// build those lines belong to is closed: either a build that had already
+// closed past after, or the next Close.
+func (h *Hub) Subscribe(appID string, after uint64) (<-chan Entry, func()) {
+	return h.subscribe(appID, after, true)
+}
+
+// Follow is like Subscribe but keeps going across builds until unsubscribed.
+func (h *Hub) Follow(appID string, after uint64) (<-chan Entry, func()) {
+	return h.subscribe(appID, after, false)
+}
+
+func (h *Hub) subscribe(appID string, after uint64, untilClose bool) (<-chan Entry, func()) {
+	ch := make(chan Entry, 64)
+	done := make(chan struct{})
+
+	h.mu.Lock()
+	a := h.app(appID)
+	a.subs++
+	closes := a.closes
+	// A sequence number past the last line was handed out before the
+	// numbering restarted: replay from the start of the ring instead of
+	// waiting, silently, for the numbers to catch up
+	var reset *Entry
+	if last := a.next - 1; after > last {
+		after = a.oldest() - 1
+		reset = &Entry{Seq: after, Line: fmt.Sprintf("log reset: resuming from line %d", after+1)}
+	}
+	h.mu.Unlock()
+
+	send := func(e Entry) bool {
+		select {
+		case ch <- e:
+			return true
+		case <-done:
+			return false
+		}
+	}
+
+	go func() {
+		defer close(ch)
+		defer func() {
+			h.mu.Lock()
+			a.subs--
+			if a.removed && a.subs == 0 && h.apps[appID] == a {
+				delete(h.apps, appID)
+			}
+			h.mu.Unlock()
+		}()
+		if reset != nil && !send(*reset) {
+			return
+		}
+		cursor := after
+		for {
+			h.mu.Lock()
+			entries, skipped := a.since(cursor)
+			open, closedAt, closed := a.open, a.closedAt, a.closes > closes
+			removed, last := a.removed, a.next-1
+			wake := a.wake
+			h.mu.Unlock()
+
+			if skipped > 0 {
+				cursor += skipped
+				if !send(Entry{Seq: cursor, Line: fmt.Sprintf("lines skipped: %d", skipped)}) {
+					return
+				}
+			}
+			for _, e := range entries {
+				if !send(e) {
+					return
+				}
+				cursor = e.Seq
+			}
+
+			if untilClose && !open && cursor >= closedAt && (closedAt > after || closed) {
+				return
+			}
+			if removed && cursor >= last {
+				return
+			}
+			if len(entries) == 0 && skipped == 0 {
+				select {
+				case <-wake:
+				case <-done:
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	unsub := func() { once.Do(func() { close(done) }) }
+	return ch, unsub
 }
@@ -1,13 +1,15 @@
 package logstream
 
 import (
-	"bufio"
 	"context"
-	"os"
 	"time"
+
+	"github.com/reviewapps-dev/rad/internal/logwriter"
 )
 
-// Tailer polls a log file for new lines and sends them on a channel.
+// Tailer polls a log file for new lines and sends them on a channel. It
+// follows the file across rotation: the old file is drained before the new
+// one is opened.
 type Tailer struct {
 	path     string
 	backlog  int
@@ -37,11 +39,11 @@ func (t *Tailer) run(ctx context.Context, ch chan<- string) {
 	defer close(ch)
 
 	// Wait for the file to exist (it may not be created yet)
-	var offset int64
+	var end Position
 	for {
-		info, err := os.Stat(t.path)
+		var err error
+		end, err = EndPosition(t.path)
 		if err == nil {
-			offset = t.sendBacklog(ctx, ch, info.Size())
 			break
 		}
 		select {
@@ -51,86 +53,35 @@ func (t *Tailer) run(ctx context.Context, ch chan<- string) {
 		}
 	}
 
-	// Poll for new content
-	ticker := time.NewTicker(t.interval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			info, err := os.Stat(t.path)
-			if err != nil {
-				continue
-			}
-			newSize := info.Size()
-			if newSize <= offset {
-				if newSize < offset {
-					// File was truncated — reset
-					offset = 0
-				}
-				continue
-			}
-			offset = t.sendNewLines(ctx, ch, offset)
-		}
-	}
-}
-
-// sendBacklog reads the file and sends the last N lines. Returns the file offset after reading.
-func (t *Tailer) sendBacklog(ctx context.Context, ch chan<- string, fileSize int64) int64 {
-	f, err := os.Open(t.path)
-	if err != nil {
-		return 0
-	}
-	defer f.Close()
-
-	// Read all lines to get the tail
-	var lines []string
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	// Backlog may reach into rotated segments. It stops at end, where
+	// following starts, so lines written in between aren't sent twice.
+	if !t.sendBacklog(ctx, ch, end) {
+		return
 	}
 
-	start := 0
-	if len(lines) > t.backlog {
-		start = len(lines) - t.backlog
-	}
-	for _, line := range lines[start:] {
+	Follow(ctx, t.path, end, t.interval, func(line string, _ Position) bool {
 		select {
 		case ch <- line:
+			return true
 		case <-ctx.Done():
-			return fileSize
+			return false
 		}
-	}
-
-	return fileSize
+	})
 }
 
-// sendNewLines reads new content from offset and sends complete lines. Returns the new offset.
-func (t *Tailer) sendNewLines(ctx context.Context, ch chan<- string, offset int64) int64 {
-	f, err := os.Open(t.path)
+// sendBacklog sends the last N lines before end, including from rotated
+// segments. Returns false if the context was cancelled.
+func (t *Tailer) sendBacklog(ctx context.Context, ch chan<- string, end Position) bool {
+	lines, err := logwriter.TailBefore(t.path, t.backlog, end.Offset)
 	if err != nil {
-		return offset
-	}
-	defer f.Close()
-
-	if _, err := f.Seek(offset, 0); err != nil {
-		return offset
+		return true
 	}
-
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
+	for _, line := range lines {
 		select {
-		case ch <- scanner.Text():
+		case ch <- line:
 		case <-ctx.Done():
-			break
+			return false
 		}
 	}
-
-	pos, err := f.Seek(0, 1) // current position
-	if err != nil {
-		return offset
-	}
-	return pos
+	return true
 }
@@ -0,0 +1,180 @@
+package logstream
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+// watcher wakes a follower when its log file changes, via inotify on the
+// log's directory so renames (rotation) and re-creation are seen too. All
+// watchers on a directory share one inotify instance, whose events are fanned
+// out by file name, so followers don't use up the per-user inotify limits.
+type watcher struct {
+	dir  *dirWatch
+	name string
+	c    chan struct{}
+}
+
+// dirWatch is the inotify instance for one directory and the watchers on it.
+type dirWatch struct {
+	path     string
+	f        *os.File
+	watchers map[string]map[*watcher]struct{} // by file name
+	refs     int
+}
+
+var (
+	watchMu sync.Mutex
+	dirs    = make(map[string]*dirWatch)
+)
+
+func newWatcher(path string) (*watcher, error) {
+	watchMu.Lock()
+	defer watchMu.Unlock()
+
+	dirPath := filepath.Dir(path)
+	d, ok := dirs[dirPath]
+	if !ok {
+		f, err := watchDir(dirPath)
+		if err != nil {
+			return nil, err
+		}
+		d = &dirWatch{
+			path:     dirPath,
+			f:        f,
+			watchers: make(map[string]map[*watcher]struct{}),
+		}
+		dirs[dirPath] = d
+		go d.read()
+	}
+
+	w := &watcher{
+		dir:  d,
+		name: filepath.Base(path),
+		c:    make(chan struct{}, 1),
+	}
+	if d.watchers[w.name] == nil {
+		d.watchers[w.name] = make(map[*watcher]struct{})
+	}
+	d.watchers[w.name][w] = struct{}{}
+	d.refs++
+	return w, nil
+}
+
+// watchDir starts an inotify instance watching dir.
+func watchDir(dir string) (*os.File, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	mask := uint32(syscall.IN_MODIFY | syscall.IN_CREATE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_DELETE | syscall.IN_CLOSE_WRITE)
+	if _, err := syscall.InotifyAddWatch(fd, dir, mask); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	// A non-blocking fd goes through the runtime poller, so Close unblocks Read
+	return os.NewFile(uintptr(fd), "inotify"), nil
+}
+
+func (d *dirWatch) read() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := d.f.Read(buf)
+		if err != nil {
+			return
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			nameStart := off + syscall.SizeofInotifyEvent
+			name := string(buf[nameStart : nameStart+int(ev.Len)])
+			off = nameStart + int(ev.Len)
+
+			// Events were lost: wake everyone to recheck their file
+			if ev.Mask&syscall.IN_Q_OVERFLOW != 0 {
+				d.notifyAll()
+				continue
+			}
+			// The directory is gone. Watchers of a re-created one get a new
+			// instance; these ones fall back to polling.
+			if ev.Mask&syscall.IN_IGNORED != 0 {
+				watchMu.Lock()
+				if dirs[d.path] == d {
+					delete(dirs, d.path)
+				}
+				watchMu.Unlock()
+				d.notifyAll()
+				continue
+			}
+			d.notify(trimNul(name))
+		}
+	}
+}
+
+// notify wakes the watchers of the named file.
+func (d *dirWatch) notify(name string) {
+	watchMu.Lock()
+	defer watchMu.Unlock()
+	for w := range d.watchers[name] {
+		w.wake()
+	}
+}
+
+func (d *dirWatch) notifyAll() {
+	watchMu.Lock()
+	defer watchMu.Unlock()
+	for _, ws := range d.watchers {
+		for w := range ws {
+			w.wake()
+		}
+	}
+}
+
+func (w *watcher) wake() {
+	select {
+	case w.c <- struct{}{}:
+	default:
+	}
+}
+
+// C receives a value after the log has changed.
+func (w *watcher) C() <-chan struct{} {
+	return w.c
+}
+
+// Close stops the watcher. The directory's inotify instance is closed with
+// its last watcher.
+func (w *watcher) Close() {
+	watchMu.Lock()
+	defer watchMu.Unlock()
+	d := w.dir
+	ws, ok := d.watchers[w.name]
+	if !ok {
+		return
+	}
+	if _, ok := ws[w]; !ok {
+		return
+	}
+	delete(ws, w)
+	if len(ws) == 0 {
+		delete(d.watchers, w.name)
+	}
+	d.refs--
+	if d.refs == 0 {
+		if dirs[d.path] == d {
+			delete(dirs, d.path)
+		}
+		d.f.Close()
+	}
+}
+
+func trimNul(s string) string {
+	for i := 0; i < len(s); i++ {
+		if s[i] == 0 {
+			return s[:i]
+		}
+	}
+	return s
+}
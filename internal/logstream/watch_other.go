@@ -0,0 +1,16 @@
+//go:build !linux
+
+package logstream
+
+import "errors"
+
+// watcher is only implemented with inotify; elsewhere Follow polls.
+type watcher struct{}
+
+func newWatcher(path string) (*watcher, error) {
+	return nil, errors.New("file watching not supported on this platform")
+}
+
+func (w *watcher) C() <-chan struct{} { return nil }
+
+func (w *watcher) Close() {}
@@ -0,0 +1,120 @@
+package logwriter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Streams a line can come from.
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+)
+
+// lineTime is the timestamp format at the start of each captured line.
+const lineTime = "2006-01-02T15:04:05.000Z07:00"
+
+// Line is one captured line of process output:
+//
+//	2024-05-01T12:00:00.000Z web stderr Started GET "/" for 127.0.0.1
+type Line struct {
+	Time    time.Time
+	Process string
+	Stream  string
+	Text    string
+}
+
+func (l Line) String() string {
+	if l.Time.IsZero() {
+		return l.Text
+	}
+	return l.Time.UTC().Format(lineTime) + " " + l.Process + " " + l.Stream + " " + l.Text
+}
+
+// ParseLine splits a captured line into its parts. Lines written before
+// capture was structured come back with only Text set.
+func ParseLine(s string) Line {
+	parts := strings.SplitN(s, " ", 4)
+	if len(parts) < 3 || (parts[2] != StreamStdout && parts[2] != StreamStderr) {
+		return Line{Text: s}
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return Line{Text: s}
+	}
+	l := Line{Time: t, Process: parts[1], Stream: parts[2]}
+	if len(parts) == 4 {
+		l.Text = parts[3]
+	}
+	return l
+}
+
+// Query selects lines from a log. Zero fields don't filter.
+type Query struct {
+	Since time.Time
+	Until time.Time
+	Grep  *regexp.Regexp
+	Limit int // newest lines to return
+}
+
+func (q Query) match(l Line) bool {
+	if !q.Since.IsZero() || !q.Until.IsZero() {
+		if l.Time.IsZero() {
+			return false
+		}
+		if !q.Since.IsZero() && l.Time.Before(q.Since) {
+			return false
+		}
+		if !q.Until.IsZero() && l.Time.After(q.Until) {
+			return false
+		}
+	}
+	if q.Grep != nil && !q.Grep.MatchString(l.Text) {
+		return false
+	}
+	return true
+}
+
+// Read returns the newest lines of a log that match q, oldest first. It
+// reaches back into rotated segments until it has q.Limit lines or the
+// segments start before q.Since.
+func Read(path string, q Query) ([]Line, error) {
+	files := append(Segments(path), path)
+	found := false
+	var lines []Line
+
+	for i := len(files) - 1; i >= 0; i-- {
+		raw, err := readLines(files[i])
+		if err != nil {
+			continue
+		}
+		found = true
+
+		var matched []Line
+		for _, s := range raw {
+			if l := ParseLine(s); q.match(l) {
+				matched = append(matched, l)
+			}
+		}
+		lines = append(matched, lines...)
+
+		if q.Limit > 0 && len(lines) >= q.Limit {
+			break
+		}
+		if !q.Since.IsZero() && len(raw) > 0 {
+			if first := ParseLine(raw[0]); !first.Time.IsZero() && first.Time.Before(q.Since) {
+				break
+			}
+		}
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no log at %s", path)
+	}
+	if q.Limit > 0 && len(lines) > q.Limit {
+		lines = lines[len(lines)-q.Limit:]
+	}
+	return lines, nil
+}
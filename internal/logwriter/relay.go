@@ -0,0 +1,189 @@
+package logwriter
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/process"
+)
+
+// RelayCommand is the rad subcommand that copies a process's output into a
+// rotating log. It runs as its own process, in the app process's group, so
+// output keeps flowing across rad restarts and self-updates, and it goes
+// away when the process is stopped.
+const RelayCommand = "log-writer"
+
+// Start starts cmd with stdout and stderr piped through a log relay that
+// writes them to path as timestamped lines tagged with name and the stream.
+func Start(cmd *exec.Cmd, path, name string, opts Options) (*process.Info, error) {
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("log pipe: %w", err)
+	}
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		outR.Close()
+		outW.Close()
+		return nil, fmt.Errorf("log pipe: %w", err)
+	}
+	cmd.Stdout = outW
+	cmd.Stderr = errW
+
+	info, err := process.Start(cmd)
+	// The child holds its own descriptors
+	outW.Close()
+	errW.Close()
+	if err != nil {
+		outR.Close()
+		errR.Close()
+		return nil, err
+	}
+	defer outR.Close()
+	defer errR.Close()
+
+	if err := startRelay(outR, errR, path, name, info.PID, opts); err != nil {
+		process.Stop(info.PID)
+		return nil, fmt.Errorf("start log relay: %w", err)
+	}
+	return info, nil
+}
+
+// startRelay runs `rad log-writer` with stdout on its stdin and stderr on
+// fd 3.
+func startRelay(stdout, stderr *os.File, path, name string, pgid int, opts Options) error {
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	args := []string{
+		RelayCommand,
+		"-path", path,
+		"-process", name,
+		"-max-size", strconv.FormatInt(opts.MaxSize, 10),
+		"-max-age", opts.MaxAge.String(),
+		"-keep", strconv.Itoa(opts.Keep),
+		"-compress=" + strconv.FormatBool(opts.Compress),
+	}
+
+	newRelay := func(pgid int) *exec.Cmd {
+		cmd := exec.Command(self, args...)
+		cmd.Stdin = stdout
+		cmd.ExtraFiles = []*os.File{stderr}
+		cmd.Stderr = os.Stderr
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true, Pgid: pgid}
+		return cmd
+	}
+
+	relay := newRelay(pgid)
+	if err := relay.Start(); err != nil {
+		// The process may have exited already, taking its group with it.
+		// Drain whatever it wrote from a group of our own.
+		relay = newRelay(0)
+		if err := relay.Start(); err != nil {
+			return err
+		}
+	}
+	go relay.Wait()
+	return nil
+}
+
+// RunRelay is the entry point of `rad log-writer`. It copies stdin (stdout of
+// the process) and fd 3 (its stderr) into a rotating log until the write ends
+// of both pipes are closed, i.e. until the process and everything it spawned
+// have exited.
+func RunRelay(args []string) error {
+	fs := flag.NewFlagSet(RelayCommand, flag.ExitOnError)
+	path := fs.String("path", "", "log file to write")
+	name := fs.String("process", "web", "process name to tag lines with")
+	maxSize := fs.Int64("max-size", 0, "rotate after this many bytes (0 disables)")
+	maxAge := fs.Duration("max-age", 0, "rotate after this long (0 disables)")
+	keep := fs.Int("keep", 5, "rotated segments to keep")
+	compress := fs.Bool("compress", true, "gzip rotated segments")
+	fs.Parse(args)
+
+	if *path == "" {
+		return fmt.Errorf("-path is required")
+	}
+
+	// Stopping the app signals the whole process group. Keep going until
+	// the pipe closes so the app's last lines aren't lost.
+	signal.Ignore(syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	log.SetPrefix("log-writer: ")
+
+	w, err := Open(*path, Options{
+		MaxSize:  *maxSize,
+		MaxAge:   *maxAge,
+		Keep:     *keep,
+		Compress: *compress,
+	})
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	streams := []struct {
+		r      io.Reader
+		stream string
+	}{
+		{os.Stdin, StreamStdout},
+		{os.NewFile(3, "stderr"), StreamStderr},
+	}
+	for i, s := range streams {
+		wg.Add(1)
+		go func(i int, r io.Reader, stream string) {
+			defer wg.Done()
+			errs[i] = copyLines(w, r, *name, stream)
+		}(i, s.r, s.stream)
+	}
+	wg.Wait()
+
+	if err := w.Close(); err != nil {
+		return err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyLines reads lines from r and writes each one to w, stamped with the
+// time it was read. A final line without a newline is written at EOF.
+func copyLines(w io.Writer, r io.Reader, name, stream string) error {
+	br := bufio.NewReader(r)
+	for {
+		text, err := br.ReadString('\n')
+		if text != "" {
+			line := Line{
+				Time:    time.Now(),
+				Process: name,
+				Stream:  stream,
+				Text:    strings.TrimRight(text, "\r\n"),
+			}
+			// One write per line so stdout and stderr never interleave mid-line
+			if _, werr := io.WriteString(w, line.String()+"\n"); werr != nil {
+				return werr
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package logwriter
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/config"
+)
+
+// segmentTime is the timestamp format of rotated segments: {path}.{ts}[.gz]
+const segmentTime = "20060102T150405"
+
+// Options controls when a log is rotated and how many old segments are kept.
+type Options struct {
+	MaxSize  int64         // rotate once the file would grow past this many bytes; 0 disables
+	MaxAge   time.Duration // rotate once the file has been written to for this long; 0 disables
+	Keep     int           // rotated segments to keep; older ones are deleted
+	Compress bool          // gzip rotated segments
+}
+
+// OptionsFromConfig converts the [logs] section of config.toml.
+func OptionsFromConfig(cfg config.LogsConfig) Options {
+	return Options{
+		MaxSize:  int64(cfg.MaxSizeMB) * 1024 * 1024,
+		MaxAge:   time.Duration(cfg.MaxAgeHours) * time.Hour,
+		Keep:     cfg.Keep,
+		Compress: cfg.Compress,
+	}
+}
+
+// Writer appends to a log file and rotates it by size or age. Rotated
+// segments are renamed to {path}.{timestamp} and, if enabled, gzipped in the
+// background.
+type Writer struct {
+	path string
+	opts Options
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+	wg     sync.WaitGroup
+}
+
+func Open(path string, opts Options) (*Writer, error) {
+	w := &Writer{path: path, opts: opts}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.f = f
+	w.size = info.Size()
+	w.opened = time.Now()
+	return nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.size > 0 && w.due(len(p)) {
+		if err := w.rotate(); err != nil {
+			log.Printf("logwriter: rotate %s: %v", w.path, err)
+		}
+	}
+
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// due reports whether writing n more bytes calls for a rotation.
+func (w *Writer) due(n int) bool {
+	if w.opts.MaxSize > 0 && w.size+int64(n) > w.opts.MaxSize {
+		return true
+	}
+	if w.opts.MaxAge > 0 && time.Since(w.opened) > w.opts.MaxAge {
+		return true
+	}
+	return false
+}
+
+// rotate moves the current file aside and starts a new one. Must hold w.mu.
+func (w *Writer) rotate() error {
+	if err := w.f.Close(); err != nil {
+		return err
+	}
+
+	segment := w.path + "." + time.Now().UTC().Format(segmentTime)
+	for i := 1; exists(segment) || exists(segment+".gz"); i++ {
+		segment = fmt.Sprintf("%s.%s-%d", w.path, time.Now().UTC().Format(segmentTime), i)
+	}
+	if err := os.Rename(w.path, segment); err != nil {
+		// Keep writing to the old file rather than losing output
+		if openErr := w.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		if w.opts.Compress {
+			if err := compress(segment); err != nil {
+				log.Printf("logwriter: compress %s: %v", segment, err)
+			}
+		}
+		w.prune()
+	}()
+	return nil
+}
+
+// prune deletes the oldest segments beyond opts.Keep.
+func (w *Writer) prune() {
+	if w.opts.Keep <= 0 {
+		return
+	}
+	segments := Segments(w.path)
+	if len(segments) <= w.opts.Keep {
+		return
+	}
+	for _, s := range segments[:len(segments)-w.opts.Keep] {
+		os.Remove(s)
+	}
+}
+
+// Close closes the file and waits for any background compression.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	err := w.f.Close()
+	w.mu.Unlock()
+	w.wg.Wait()
+	return err
+}
+
+// compress gzips path to path.gz and removes path.
+func compress(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmp := path + ".gz.tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	if _, err := io.Copy(gz, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Segments returns the rotated segments of a log, oldest first.
+func Segments(path string) []string {
+	matches, _ := filepath.Glob(path + ".*")
+	var segments []string
+	for _, m := range matches {
+		suffix := strings.TrimPrefix(m, path+".")
+		if strings.HasSuffix(suffix, ".tmp") || len(suffix) < len(segmentTime) {
+			continue
+		}
+		if _, err := time.Parse(segmentTime, suffix[:len(segmentTime)]); err != nil {
+			continue
+		}
+		segments = append(segments, m)
+	}
+	sort.Strings(segments)
+	return segments
+}
+
+// Tail returns the last n lines of a log, reaching back into rotated
+// segments if the current file has fewer than n. Uncompressed files are read
+// backwards from the end, so a large log costs only what's returned.
+func Tail(path string, n int) ([]string, error) {
+	return TailBefore(path, n, -1)
+}
+
+// TailBefore is Tail with the current file read only up to byte end, so
+// lines written after that point (e.g. while a follower is being set up)
+// aren't included. A negative end reads to the end of the file.
+func TailBefore(path string, n int, end int64) ([]string, error) {
+	lines, err := lastLines(path, n, end)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	found := err == nil
+
+	segments := Segments(path)
+	for i := len(segments) - 1; i >= 0 && len(lines) < n; i-- {
+		var older []string
+		var err error
+		if strings.HasSuffix(segments[i], ".gz") {
+			older, err = readLines(segments[i])
+		} else {
+			older, err = lastLines(segments[i], n-len(lines), -1)
+		}
+		if err != nil {
+			continue
+		}
+		found = true
+		lines = append(older, lines...)
+	}
+
+	if !found {
+		return nil, fmt.Errorf("no log at %s", path)
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines, nil
+}
+
+// tailBlock is how much lastLines reads per step backwards.
+const tailBlock = 64 * 1024
+
+// lastLines returns the last n lines of an uncompressed file by reading
+// blocks backwards from EOF, or from byte end if that's earlier and not
+// negative, until it has seen n line breaks.
+func lastLines(path string, n int, end int64) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	pos := info.Size()
+	if end >= 0 && end < pos {
+		pos = end
+	}
+	if n <= 0 || pos == 0 {
+		return nil, nil
+	}
+
+	var data []byte
+	breaks := 0
+	for pos > 0 && breaks <= n {
+		size := int64(tailBlock)
+		if size > pos {
+			size = pos
+		}
+		pos -= size
+		block := make([]byte, size)
+		if _, err := f.ReadAt(block, pos); err != nil && err != io.EOF {
+			return nil, err
+		}
+		breaks += strings.Count(string(block), "\n")
+		data = append(block, data...)
+	}
+
+	text := strings.TrimSuffix(string(data), "\n")
+	lines := strings.Split(text, "\n")
+	if pos > 0 {
+		// The first line is most likely cut off
+		lines = lines[1:]
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	for i, l := range lines {
+		lines[i] = strings.TrimSuffix(l, "\r")
+	}
+	return lines, nil
+}
+
+// readLines reads every line of a log file, decompressing .gz segments.
+// Lines of any length are returned whole.
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return ReadAllLines(r)
+}
+
+// ReadAllLines reads r to the end and splits it into lines. Unlike
+// bufio.Scanner it has no line length limit.
+func ReadAllLines(r io.Reader) ([]string, error) {
+	var lines []string
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			lines = append(lines, strings.TrimRight(line, "\r\n"))
+		}
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return lines, err
+		}
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -1,34 +1,43 @@
 package monitor
 
 import (
-	"fmt"
 	"log"
-	"os"
-	"path/filepath"
-	"strings"
-	"syscall"
+	"sync"
 	"time"
 
 	"github.com/reviewapps-dev/rad/internal/app"
 	"github.com/reviewapps-dev/rad/internal/config"
+	"github.com/reviewapps-dev/rad/internal/health"
+	"github.com/reviewapps-dev/rad/internal/logstream"
 	"github.com/reviewapps-dev/rad/internal/process"
-	"github.com/reviewapps-dev/rad/internal/rv"
+	"github.com/reviewapps-dev/rad/internal/supervisor"
 )
 
-// Monitor periodically checks running app processes and restarts any that have crashed.
+// healthTimeout is how long a restarted web process has to pass its health
+// check before a health event is published.
+const healthTimeout = 30 * time.Second
+
+// Monitor periodically checks running app processes and restarts any that
+// have crashed. A restarted web process is health checked in the background.
 type Monitor struct {
 	store    *app.Store
 	cfg      *config.Config
+	sup      *supervisor.Supervisor
 	interval time.Duration
 	done     chan struct{}
+
+	mu       sync.Mutex
+	checking map[string]bool // apps with a health check in flight
 }
 
-func New(cfg *config.Config, store *app.Store, interval time.Duration) *Monitor {
+func New(cfg *config.Config, store *app.Store, sup *supervisor.Supervisor, interval time.Duration) *Monitor {
 	return &Monitor{
 		store:    store,
 		cfg:      cfg,
+		sup:      sup,
 		interval: interval,
 		done:     make(chan struct{}),
+		checking: make(map[string]bool),
 	}
 }
 
@@ -70,7 +79,7 @@ func (m *Monitor) check() {
 			if proc.PID <= 0 {
 				continue
 			}
-			if isAlive(proc.PID) {
+			if process.IsAlive(proc.PID) {
 				continue
 			}
 
@@ -80,78 +89,45 @@ func (m *Monitor) check() {
 	}
 }
 
-// isAlive checks if a process is still running by sending signal 0.
-func isAlive(pid int) bool {
-	p, err := os.FindProcess(pid)
-	if err != nil {
-		return false
-	}
-	err = p.Signal(syscall.Signal(0))
-	return err == nil
-}
-
 func (m *Monitor) restartProcess(state *app.AppState, name string) {
-	cmd, ok := state.ProcessCommands[name]
-	if !ok {
-		log.Printf("monitor: no saved command for %s/%s, cannot restart", state.AppID, name)
-		return
-	}
-
-	// Expand $PORT for web process
-	if name == "web" && state.Port > 0 {
-		cmd = strings.ReplaceAll(cmd, "$PORT", fmt.Sprintf("%d", state.Port))
-	}
-
-	repoDir := filepath.Join(state.AppDir, "repo")
-	envSlice := m.loadAppEnv(state)
-
-	logPath := m.processLogPath(state.AppID, name)
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	proc, err := m.sup.Respawn(state, name)
 	if err != nil {
-		log.Printf("monitor: open log for %s/%s: %v", state.AppID, name, err)
-		return
-	}
-
-	execCmd := rv.ExecInDir(repoDir, state.RubyVersion, envSlice, cmd)
-	execCmd.Stdout = logFile
-	execCmd.Stderr = logFile
-
-	info, err := process.Start(execCmd)
-	if err != nil {
-		logFile.Close()
 		log.Printf("monitor: restart %s/%s failed: %v", state.AppID, name, err)
 		return
 	}
 
-	proc := app.ProcessInfo{Name: name, PID: info.PID}
+	log.Printf("monitor: restarted %s/%s (new pid=%d)", state.AppID, name, proc.PID)
 	if name == "web" {
-		proc.Port = state.Port
+		go m.checkHealth(state, proc.Port)
 	}
-	_ = m.store.SetProcess(state.AppID, proc)
-
-	log.Printf("monitor: restarted %s/%s (new pid=%d)", state.AppID, name, info.PID)
 }
 
-func (m *Monitor) loadAppEnv(state *app.AppState) []string {
-	var envSlice []string
-	envPath := filepath.Join(state.AppDir, ".env")
-	data, err := os.ReadFile(envPath)
-	if err != nil {
-		return envSlice
+// checkHealth waits for a restarted web process to pass its health check
+// and publishes a health event if it doesn't. One check runs per app.
+func (m *Monitor) checkHealth(state *app.AppState, port int) {
+	m.mu.Lock()
+	if m.checking[state.AppID] {
+		m.mu.Unlock()
+		return
 	}
-	for _, line := range strings.Split(string(data), "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
+	m.checking[state.AppID] = true
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.checking, state.AppID)
+		m.mu.Unlock()
+	}()
+
+	// In dev mode, don't set Host header — just use localhost
+	host := ""
+	if !m.cfg.Dev {
+		host = state.Subdomain
+		if host == "" {
+			host = state.AppID
 		}
-		envSlice = append(envSlice, line)
 	}
-	return envSlice
-}
-
-func (m *Monitor) processLogPath(appID, name string) string {
-	if name == "web" {
-		return filepath.Join(m.cfg.Paths.LogDir, appID+".log")
+	if err := health.Check(port, host, healthTimeout, 2*time.Second, ""); err != nil {
+		log.Printf("monitor: %s/web failed its health check after restart: %v", state.AppID, err)
+		m.sup.Event(state.AppID, logstream.EventHealth, "web", "health check failed after restart: %v", err)
 	}
-	return filepath.Join(m.cfg.Paths.LogDir, appID+"."+name+".log")
 }
@@ -33,6 +33,19 @@ func (a *Allocator) Allocate(appID string) (int, error) {
 		}
 	}
 
+	return a.allocateLocked(appID)
+}
+
+// AllocateAdditional assigns a second port to an app that already has one.
+// Used for blue/green redeploys, where the new web process boots next to the
+// old one before traffic is switched over.
+func (a *Allocator) AllocateAdditional(appID string) (int, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.allocateLocked(appID)
+}
+
+func (a *Allocator) allocateLocked(appID string) (int, error) {
 	for port := minPort; port <= maxPort; port++ {
 		if _, taken := a.assigned[port]; taken {
 			continue
@@ -47,17 +60,24 @@ func (a *Allocator) Allocate(appID string) (int, error) {
 	return 0, fmt.Errorf("no available ports in range %d-%d", minPort, maxPort)
 }
 
+// Release frees every port assigned to an app.
 func (a *Allocator) Release(appID string) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	for port, id := range a.assigned {
 		if id == appID {
 			delete(a.assigned, port)
-			return
 		}
 	}
 }
 
+// ReleasePort frees a single port, e.g. the old port after a blue/green swap.
+func (a *Allocator) ReleasePort(port int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.assigned, port)
+}
+
 // Reserve marks a port as assigned to an app. Used on startup to restore
 // port assignments from persisted state.
 func (a *Allocator) Reserve(appID string, port int) {
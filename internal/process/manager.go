@@ -21,6 +21,10 @@ func Start(cmd *exec.Cmd) (*Info, error) {
 		return nil, fmt.Errorf("start process: %w", err)
 	}
 
+	// Reap the process when it exits. Otherwise it lingers as a zombie,
+	// which still answers signal 0 and looks alive to IsAlive.
+	go cmd.Wait()
+
 	return &Info{
 		Cmd: cmd,
 		PID: cmd.Process.Pid,
@@ -41,23 +45,34 @@ func Stop(pid int) error {
 		proc.Signal(syscall.SIGTERM)
 	}
 
-	// Wait up to 10 seconds for graceful shutdown
-	done := make(chan struct{})
-	go func() {
-		proc.Wait()
-		close(done)
-	}()
-
-	select {
-	case <-done:
-		return nil
-	case <-time.After(10 * time.Second):
-		// Force kill the process group
-		if pgid, err := syscall.Getpgid(pid); err == nil {
-			syscall.Kill(-pgid, syscall.SIGKILL)
-		} else {
-			proc.Kill()
+	// Wait up to 10 seconds for graceful shutdown. Polls rather than waits:
+	// our own children are reaped by Start, and processes adopted after a
+	// rad restart aren't our children at all.
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if !IsAlive(pid) {
+			return nil
 		}
-		return nil
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// Force kill the process group
+	if pgid, err := syscall.Getpgid(pid); err == nil {
+		syscall.Kill(-pgid, syscall.SIGKILL)
+	} else {
+		proc.Kill()
+	}
+	return nil
+}
+
+// IsAlive checks if a process is still running by sending signal 0.
+func IsAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
 	}
+	return p.Signal(syscall.Signal(0)) == nil
 }
@@ -0,0 +1,20 @@
+//go:build !linux
+
+package pty
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+var errUnsupported = errors.New("pty: not supported on this platform")
+
+// Start is only implemented on Linux.
+func Start(cmd *exec.Cmd, cols, rows uint16) (*os.File, error) {
+	return nil, errUnsupported
+}
+
+func Resize(master *os.File, cols, rows uint16) error {
+	return errUnsupported
+}
@@ -0,0 +1,89 @@
+package reaper
+
+import (
+	"log"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/teardown"
+)
+
+// maxAttempts is how many failed teardowns of an app the reaper retries
+// before leaving it for an explicit DELETE. retryBackoff is the wait after
+// the first failure, doubled after each one after that.
+const (
+	maxAttempts  = 5
+	retryBackoff = 5 * time.Minute
+)
+
+// Reaper periodically tears down apps whose expires_at has passed, so apps
+// whose DELETE never arrives don't linger forever. Teardowns that keep
+// failing are retried with a growing delay, then left alone.
+type Reaper struct {
+	store    *app.Store
+	td       *teardown.Teardown
+	interval time.Duration
+	done     chan struct{}
+}
+
+func New(store *app.Store, td *teardown.Teardown, interval time.Duration) *Reaper {
+	return &Reaper{
+		store:    store,
+		td:       td,
+		interval: interval,
+		done:     make(chan struct{}),
+	}
+}
+
+func (r *Reaper) Start() {
+	go r.loop()
+	log.Printf("reaper: started (interval=%s)", r.interval)
+}
+
+func (r *Reaper) Stop() {
+	close(r.done)
+	log.Printf("reaper: stopped")
+}
+
+func (r *Reaper) loop() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.check()
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *Reaper) check() {
+	now := time.Now()
+	for _, state := range r.store.List() {
+		if state.ExpiresAt.IsZero() || now.Before(state.ExpiresAt) {
+			continue
+		}
+		// Leave apps mid-deploy alone; they're picked up once the deploy finishes
+		switch state.Status {
+		case app.StatusRunning, app.StatusFailed, app.StatusStopped, app.StatusSleeping:
+		default:
+			continue
+		}
+		if n := state.TeardownFailures; n > 0 {
+			if n >= maxAttempts {
+				continue
+			}
+			if now.Before(state.TeardownFailedAt.Add(retryBackoff << (n - 1))) {
+				continue
+			}
+			log.Printf("reaper: retrying teardown of %s (%d failed so far)", state.AppID, n)
+		}
+
+		log.Printf("reaper: %s expired at %s, tearing down", state.AppID, state.ExpiresAt.Format(time.RFC3339))
+		if err := r.td.Enqueue(state, teardown.StatusExpired); err != nil {
+			log.Printf("reaper: teardown %s: %v", state.AppID, err)
+		}
+	}
+}
@@ -42,6 +42,7 @@ type Config struct {
 		Interval int    `yaml:"interval"`
 	} `yaml:"health_check"`
 	Processes      map[string]string `yaml:"processes"`
+	Scale          map[string]int    `yaml:"scale"` // Instance count per process type (default 1)
 	SystemPackages []string          `yaml:"system_packages"`
 }
 
@@ -2,6 +2,7 @@ package rv
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"strings"
@@ -15,10 +16,11 @@ func findBin() string {
 	return "rv"
 }
 
-func Install(rubyVersion string) error {
+// Install installs a Ruby version, writing rv's output to out.
+func Install(rubyVersion string, out io.Writer) error {
 	cmd := exec.Command(findBin(), "ruby", "install", rubyVersion)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = out
+	cmd.Stderr = out
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("rv ruby install %s: %w", rubyVersion, err)
 	}
@@ -86,13 +88,14 @@ func ListInstalled() []string {
 	return versions
 }
 
-// CleanInstall runs `rv clean-install` in the given directory.
-func CleanInstall(dir string, env []string) error {
+// CleanInstall runs `rv clean-install` in the given directory, writing its
+// output to out.
+func CleanInstall(dir string, env []string, out io.Writer) error {
 	cmd := exec.Command(findBin(), "clean-install")
 	cmd.Dir = dir
 	cmd.Env = append(os.Environ(), env...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
+	cmd.Stdout = out
+	cmd.Stderr = out
 
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("rv clean-install: %w", err)
@@ -3,25 +3,28 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"os/exec"
-	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/reviewapps-dev/rad/internal/admission"
 	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/auth"
 	"github.com/reviewapps-dev/rad/internal/buildqueue"
-	"github.com/reviewapps-dev/rad/internal/callback"
-	"github.com/reviewapps-dev/rad/internal/database"
-	"github.com/reviewapps-dev/rad/internal/deploy"
+	"github.com/reviewapps-dev/rad/internal/execjob"
 	"github.com/reviewapps-dev/rad/internal/fnm"
-	"github.com/reviewapps-dev/rad/internal/process"
-	"github.com/reviewapps-dev/rad/internal/reviewappsyml"
+	"github.com/reviewapps-dev/rad/internal/logging"
+	"github.com/reviewapps-dev/rad/internal/logwriter"
 	"github.com/reviewapps-dev/rad/internal/rv"
+	"github.com/reviewapps-dev/rad/internal/sleeper"
+	"github.com/reviewapps-dev/rad/internal/teardown"
 	"github.com/reviewapps-dev/rad/internal/updater"
 	"github.com/reviewapps-dev/rad/internal/version"
 )
@@ -62,7 +65,14 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleListApps(w http.ResponseWriter, r *http.Request) {
-	apps := s.store.List()
+	// Tokens limited to some apps only see those
+	token := tokenFrom(r)
+	apps := []*app.AppState{}
+	for _, state := range s.store.List() {
+		if token.AllowsApp(state.AppID) {
+			apps = append(apps, state)
+		}
+	}
 	writeJSON(w, http.StatusOK, map[string]any{
 		"apps": apps,
 	})
@@ -98,6 +108,14 @@ func (s *Server) handleGetAppStatus(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Build enriched response (includes all AppState fields plus computed ones)
+	// Lines of the latest build, kept for clients that predate builds
+	latestBuildLog := []string{}
+	if build, ok := findBuild(state, ""); ok {
+		if lines, err := logging.ReadBuildLog(s.cfg.Paths.LogDir, appID, build.ID); err == nil {
+			latestBuildLog = lines
+		}
+	}
+
 	resp := map[string]any{
 		"app_id":           state.AppID,
 		"repo_url":         state.RepoURL,
@@ -117,7 +135,9 @@ func (s *Server) handleGetAppStatus(w http.ResponseWriter, r *http.Request) {
 		"error":            state.Error,
 		"created_at":       state.CreatedAt,
 		"updated_at":       state.UpdatedAt,
-		"build_log":        state.BuildLog,
+		"expires_at":       state.ExpiresAt,
+		"builds":           state.Builds,
+		"build_log":        latestBuildLog,
 	}
 
 	writeJSON(w, http.StatusOK, resp)
@@ -149,6 +169,14 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "app_id is required")
 		return
 	}
+	if err := app.ValidateID(req.AppID); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if token := tokenFrom(r); !token.AllowsApp(req.AppID) {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("token %s is not allowed for app %s", token.Name, req.AppID))
+		return
+	}
 	if req.RepoURL == "" {
 		writeError(w, http.StatusBadRequest, "repo_url is required")
 		return
@@ -162,14 +190,34 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 	if req.DatabaseAdapter == "" {
 		req.DatabaseAdapter = s.cfg.Defaults.DatabaseAdapter
 	}
+	if req.TTL < 0 {
+		writeError(w, http.StatusBadRequest, "ttl must not be negative")
+		return
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		writeError(w, http.StatusBadRequest, "expires_at is in the past")
+		return
+	}
 
 	// Detect if this is a redeploy (app already exists and is running)
 	isRedeploy := false
-	if existing, err := s.store.Get(req.AppID); err == nil {
+	existing, err := s.store.Get(req.AppID)
+	if err == nil {
+		// A queued teardown would delete the state and directory of
+		// this deploy when it runs
+		if existing.Status == app.StatusTeardown {
+			writeError(w, http.StatusConflict, "teardown in progress")
+			return
+		}
 		isRedeploy = true
 		log.Printf("deploy: redeploy for %s (status=%s, pid=%d)", req.AppID, existing.Status, existing.PID)
 	}
 
+	victim, admitted := s.admit(w, req.AppID, tokenFrom(r))
+	if !admitted {
+		return
+	}
+
 	// Convert request hooks to app hooks
 	var hooks *app.Hooks
 	if req.Hooks != nil {
@@ -204,10 +252,38 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:       time.Now(),
 	}
 
+	// Carry runtime state over so the pipeline can stop the old processes
+	// and runtime scale overrides survive the redeploy
+	if isRedeploy {
+		state.Port = existing.Port
+		state.PID = existing.PID
+		state.Processes = existing.Processes
+		state.ProcessCommands = existing.ProcessCommands
+		state.ScaleOverrides = existing.ScaleOverrides
+		state.Releases = existing.Releases
+		state.Builds = existing.Builds
+		state.AppDir = existing.AppDir
+		state.AppPath = existing.AppPath
+		state.CreatedAt = existing.CreatedAt
+	}
+
+	// Expiry: explicit expires_at, then ttl (counted from this deploy), then
+	// whatever the previous deploy set, then the server default
+	switch {
+	case req.ExpiresAt != nil:
+		state.ExpiresAt = *req.ExpiresAt
+	case req.TTL > 0:
+		state.ExpiresAt = time.Now().Add(time.Duration(req.TTL) * time.Second)
+	case isRedeploy && !existing.ExpiresAt.IsZero():
+		state.ExpiresAt = existing.ExpiresAt
+	case s.cfg.Defaults.TTLHours > 0:
+		state.ExpiresAt = time.Now().Add(time.Duration(s.cfg.Defaults.TTLHours) * time.Hour)
+	}
+
 	s.store.Put(state)
 
 	redeploy := isRedeploy
-	ok := s.queue.Enqueue(buildqueue.Job{
+	job := buildqueue.Job{
 		AppID: req.AppID,
 		Fn: func(ctx context.Context) error {
 			if s.deployFn != nil {
@@ -216,7 +292,18 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 			log.Printf("deploy: no deploy function set, skipping %s", req.AppID)
 			return nil
 		},
-	})
+	}
+
+	var ok bool
+	if victim != nil {
+		// The eviction goes ahead of the deploy, and only if both fit
+		ok = s.teardown.EnqueueBefore(victim, teardown.StatusEvicted, job) == nil
+		if ok {
+			log.Printf("deploy: evicting sleeping app %s to make room for %s", victim.AppID, req.AppID)
+		}
+	} else {
+		ok = s.queue.Enqueue(job)
+	}
 
 	if !ok {
 		writeError(w, http.StatusServiceUnavailable, "build queue full")
@@ -230,7 +317,37 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (s *Server) handleTeardown(w http.ResponseWriter, r *http.Request) {
+// admit checks host limits before accepting a deploy. If the disk or app
+// limit is hit and eviction is enabled, it returns the least recently used
+// sleeping app to tear down first; the caller queues that teardown ahead of
+// the deploy, together with it. Only apps the token may tear down are
+// evicted. Writes the rejection and returns false if the deploy can't go
+// ahead.
+func (s *Server) admit(w http.ResponseWriter, appID string, token *auth.Token) (*app.AppState, bool) {
+	err := s.admission.Check(appID)
+	if err == nil {
+		return nil, true
+	}
+
+	var limitErr *admission.Error
+	if !errors.As(err, &limitErr) {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return nil, false
+	}
+
+	if limitErr.Evictable && s.cfg.Limits.EvictSleeping && token.Has(auth.ScopeTeardown) {
+		if victim := s.admission.LeastRecentlyUsedSleeping(appID, token.AllowsApp); victim != nil {
+			log.Printf("deploy: %s; %s can be evicted to make room for %s", limitErr.Reason, victim.AppID, appID)
+			return victim, true
+		}
+	}
+
+	log.Printf("deploy: rejecting %s: %s", appID, limitErr.Reason)
+	writeError(w, limitErr.Status, limitErr.Reason)
+	return nil, false
+}
+
+func (s *Server) handleRollback(w http.ResponseWriter, r *http.Request) {
 	appID := r.PathValue("app_id")
 	state, err := s.store.Get(appID)
 	if err != nil {
@@ -238,101 +355,67 @@ func (s *Server) handleTeardown(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_ = s.store.UpdateStatus(appID, app.StatusTeardown, "")
-
-	// Run before_teardown hooks (best-effort)
-	if state.AppDir != "" {
-		repoDir := filepath.Join(state.AppDir, "repo")
-		ymlPath := filepath.Join(repoDir, "reviewapps.yml")
-		if _, err := os.Stat(ymlPath); err == nil {
-			if cfg, err := reviewappsyml.Parse(ymlPath); err == nil {
-				envSlice := s.loadAppEnv(state)
-				log.Printf("teardown: running before_teardown hooks for %s", appID)
-				if err := deploy.RunHooksFromConfig(cfg, deploy.HookBeforeTeardown, repoDir, state.RubyVersion, envSlice); err != nil {
-					log.Printf("teardown: before_teardown hook error (non-fatal): %v", err)
-				}
-			}
-		}
+	switch state.Status {
+	case app.StatusRunning, app.StatusFailed, app.StatusStopped, app.StatusSleeping:
+	default:
+		writeError(w, http.StatusConflict, "app is busy (status: "+string(state.Status)+")")
+		return
 	}
 
-	// Stop all processes
-	if len(state.Processes) > 0 {
-		log.Printf("teardown: stopping %d process(es) for %s", len(state.Processes), appID)
-		for name, proc := range state.Processes {
-			if proc.PID > 0 {
-				log.Printf("teardown: stopping %s (pid=%d)", name, proc.PID)
-				if err := process.Stop(proc.PID); err != nil {
-					log.Printf("teardown: stop %s: %v", name, err)
-				}
-			}
-		}
-	} else if state.PID > 0 {
-		// Backward compat: single PID from before multi-process
-		log.Printf("teardown: stopping process %d for %s", state.PID, appID)
-		if err := process.Stop(state.PID); err != nil {
-			log.Printf("teardown: stop process: %v", err)
-		}
+	target := state.RollbackTarget()
+	if target == nil {
+		writeError(w, http.StatusConflict, "no known-good release to roll back to")
+		return
 	}
 
-	// Release port
-	s.ports.Release(appID)
-
-	// Drop PostgreSQL databases
-	databases := state.Databases
-	if len(databases) == 0 && (state.DatabaseAdapter == "postgresql" || state.DatabaseAdapter == "postgres") {
-		databases = map[string]string{"primary": state.DatabaseAdapter}
-	}
-	for name, adapter := range databases {
-		if adapter == "postgresql" || adapter == "postgres" {
-			dbCfg := &database.DBConfig{AppID: appID, Name: name, Adapter: adapter}
-			dbName := dbCfg.DBName()
-			log.Printf("teardown: dropping database %s", dbName)
-			if err := database.DropPostgresDB(dbName); err != nil {
-				log.Printf("teardown: dropdb %s: %v", dbName, err)
+	ok := s.queue.Enqueue(buildqueue.Job{
+		AppID: appID,
+		Fn: func(ctx context.Context) error {
+			if s.rollbackFn != nil {
+				return s.rollbackFn(ctx, state)
 			}
-		}
+			log.Printf("rollback: no rollback function set, skipping %s", appID)
+			return nil
+		},
+	})
+	if !ok {
+		writeError(w, http.StatusServiceUnavailable, "build queue full")
+		return
 	}
 
-	// Remove Caddy site config and reload
-	if s.caddy != nil {
-		if err := s.caddy.RemoveSiteConfig(appID); err != nil {
-			log.Printf("teardown: caddy remove: %v", err)
-		}
-		if err := s.caddy.Reload(); err != nil {
-			log.Printf("teardown: caddy reload: %v", err)
-		}
-	}
+	_ = s.store.UpdateStatus(appID, app.StatusQueued, "")
 
-	// Remove log files ({app_id}.log, {app_id}.*.log, {app_id}.access.log)
-	logPattern := filepath.Join(s.cfg.Paths.LogDir, appID+"*.log")
-	if matches, err := filepath.Glob(logPattern); err == nil {
-		for _, f := range matches {
-			log.Printf("teardown: removing log %s", f)
-			os.Remove(f)
-		}
-	}
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"status":     "queued",
+		"app_id":     appID,
+		"commit_sha": target.CommitSHA,
+		"message":    "rollback queued",
+	})
+}
 
-	// Remove app directory
-	if state.AppDir != "" {
-		log.Printf("teardown: removing %s", state.AppDir)
-		os.RemoveAll(state.AppDir)
+func (s *Server) handleTeardown(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	state, err := s.store.Get(appID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
 	}
 
-	if err := s.store.Delete(appID); err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+	if state.Status == app.StatusTeardown {
+		writeError(w, http.StatusConflict, "teardown already in progress")
 		return
 	}
 
-	// Send teardown callback to web app
-	if state.CallbackURL != "" {
-		client := callback.NewClient(s.cfg.API.APIKey)
-		client.SendStatus(state.CallbackURL, callback.StatusPayload{
-			AppID:  appID,
-			Status: "removed",
-		})
+	if err := s.teardown.Enqueue(state, teardown.StatusRemoved); err != nil {
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+		return
 	}
 
-	writeJSON(w, http.StatusOK, map[string]string{"status": "removed", "app_id": appID})
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"status":  string(app.StatusTeardown),
+		"app_id":  appID,
+		"message": "teardown queued",
+	})
 }
 
 func (s *Server) handleRestart(w http.ResponseWriter, r *http.Request) {
@@ -350,76 +433,68 @@ func (s *Server) handleRestart(w http.ResponseWriter, r *http.Request) {
 
 	_ = s.store.UpdateStatus(appID, app.StatusStarting, "")
 
-	// Stop all existing processes
-	if len(state.Processes) > 0 {
-		log.Printf("restart: stopping %d process(es) for %s", len(state.Processes), appID)
-		for name, proc := range state.Processes {
-			if proc.PID > 0 {
-				log.Printf("restart: stopping %s (pid=%d)", name, proc.PID)
-				process.Stop(proc.PID)
-			}
-		}
-	} else if state.PID > 0 {
-		log.Printf("restart: stopping pid=%d for %s", state.PID, appID)
-		process.Stop(state.PID)
+	log.Printf("restart: restarting %d process(es) for %s", len(state.Processes), appID)
+	if err := s.sup.RestartAll(state); err != nil {
+		log.Printf("restart: %s: %v", appID, err)
+		_ = s.store.UpdateStatus(appID, app.StatusFailed, err.Error())
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
 
-	// Clear old process info
-	_ = s.store.ClearProcesses(appID)
-
-	// Re-read the .env file to build env slice
-	envSlice := s.loadAppEnv(state)
+	_ = s.store.UpdateStatus(appID, app.StatusRunning, "")
+	writeJSON(w, http.StatusOK, map[string]string{"status": "restarted", "app_id": appID})
+}
 
-	// Re-start all processes using saved commands
-	procs := state.ProcessCommands
-	if len(procs) == 0 {
-		// Fallback: just start rails server
-		procs = map[string]string{
-			"web": fmt.Sprintf("bin/rails server -p %d -e production", state.Port),
-		}
+func (s *Server) handleSleep(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	state, err := s.store.Get(appID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
 	}
 
-	repoDir := filepath.Join(state.AppDir, "repo")
-
-	for name, cmd := range procs {
-		if name == "web" {
-			cmd = strings.ReplaceAll(cmd, "$PORT", fmt.Sprintf("%d", state.Port))
-		}
-
-		log.Printf("restart: starting %s: %s", name, cmd)
+	if state.Status != app.StatusRunning {
+		writeError(w, http.StatusConflict, "app is not running (status: "+string(state.Status)+")")
+		return
+	}
 
-		logPath := s.processLogPath(appID, name)
-		logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-		if err != nil {
-			log.Printf("restart: open log: %v", err)
-			_ = s.store.UpdateStatus(appID, app.StatusFailed, err.Error())
-			writeError(w, http.StatusInternalServerError, "failed to open log file: "+err.Error())
+	if err := s.sleeper.Sleep(appID); err != nil {
+		if errors.Is(err, sleeper.ErrUnavailable) {
+			writeError(w, http.StatusConflict, err.Error())
 			return
 		}
+		log.Printf("sleep: %s: %v", appID, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
 
-		execCmd := rv.ExecInDir(repoDir, state.RubyVersion, envSlice, cmd)
-		execCmd.Stdout = logFile
-		execCmd.Stderr = logFile
+	writeJSON(w, http.StatusOK, map[string]string{"status": "sleeping", "app_id": appID})
+}
 
-		info, err := process.Start(execCmd)
-		if err != nil {
-			logFile.Close()
-			log.Printf("restart: start %s failed: %v", name, err)
-			_ = s.store.UpdateStatus(appID, app.StatusFailed, err.Error())
-			writeError(w, http.StatusInternalServerError, "failed to start process "+name+": "+err.Error())
-			return
-		}
+func (s *Server) handleWake(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	state, err := s.store.Get(appID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
 
-		proc := app.ProcessInfo{Name: name, PID: info.PID}
-		if name == "web" {
-			proc.Port = state.Port
-		}
-		_ = s.store.SetProcess(appID, proc)
-		log.Printf("restart: %s started (pid=%d)", name, info.PID)
+	switch state.Status {
+	case app.StatusSleeping, app.StatusStarting:
+	case app.StatusRunning:
+		writeJSON(w, http.StatusOK, map[string]string{"status": "running", "app_id": appID})
+		return
+	default:
+		writeError(w, http.StatusConflict, "app is not sleeping (status: "+string(state.Status)+")")
+		return
 	}
 
-	_ = s.store.UpdateStatus(appID, app.StatusRunning, "")
-	writeJSON(w, http.StatusOK, map[string]string{"status": "restarted", "app_id": appID})
+	if err := s.sleeper.Wake(appID); err != nil {
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "running", "app_id": appID})
 }
 
 func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
@@ -439,9 +514,13 @@ func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "command is required")
 		return
 	}
+	if req.Async {
+		s.startExecJob(w, state, req)
+		return
+	}
 
-	repoDir := filepath.Join(state.AppDir, "repo")
-	envSlice := s.loadAppEnv(state)
+	repoDir := state.RepoDir()
+	envSlice := s.sup.LoadEnv(state)
 
 	timeout := 30 * time.Second
 	if req.Timeout > 0 {
@@ -454,74 +533,33 @@ func (s *Server) handleExec(w http.ResponseWriter, r *http.Request) {
 	log.Printf("exec: running %q for %s (timeout=%s)", req.Command, appID, timeout)
 
 	cmd := rv.ExecInDir(repoDir, state.RubyVersion, envSlice, req.Command)
-
-	// Run with timeout using a goroutine
-	type result struct {
-		output []byte
-		err    error
-	}
-	ch := make(chan result, 1)
-	go func() {
-		out, err := cmd.CombinedOutput()
-		ch <- result{out, err}
-	}()
-
-	select {
-	case <-ctx.Done():
-		// Timeout — kill the process
-		if cmd.Process != nil {
-			cmd.Process.Kill()
-		}
-		writeJSON(w, http.StatusRequestTimeout, map[string]string{
-			"status": "timeout",
-			"app_id": appID,
-			"error":  fmt.Sprintf("command timed out after %s", timeout),
-		})
-		return
-	case res := <-ch:
-		if res.err != nil {
-			writeJSON(w, http.StatusOK, map[string]any{
-				"status": "error",
-				"app_id": appID,
-				"error":  res.err.Error(),
-				"output": string(res.output),
-			})
-			return
-		}
-
-		writeJSON(w, http.StatusOK, map[string]any{
-			"status": "success",
-			"app_id": appID,
-			"output": string(res.output),
-		})
-	}
-}
-
-// loadAppEnv reads the .env file for an app and returns the env vars as a slice.
-// Does NOT include os.Environ() — rv.ExecInDir/RunInDir already prepends that.
-func (s *Server) loadAppEnv(state *app.AppState) []string {
-	var envSlice []string
-	envPath := filepath.Join(state.AppDir, ".env")
-	data, err := os.ReadFile(envPath)
+	res, err := execjob.Run(ctx, cmd)
 	if err != nil {
-		return envSlice
-	}
-	for _, line := range strings.Split(string(data), "\n") {
-		line = strings.TrimSpace(line)
-		if line == "" || strings.HasPrefix(line, "#") {
-			continue
-		}
-		envSlice = append(envSlice, line)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
 	}
-	return envSlice
-}
 
-// processLogPath returns the log file path for a process.
-func (s *Server) processLogPath(appID, name string) string {
-	if name == "web" {
-		return filepath.Join(s.cfg.Paths.LogDir, appID+".log")
+	status, code := "success", http.StatusOK
+	switch {
+	case res.TimedOut:
+		status, code = "timeout", http.StatusRequestTimeout
+	case res.ExitCode != 0:
+		status = "error"
 	}
-	return filepath.Join(s.cfg.Paths.LogDir, appID+"."+name+".log")
+	resp := map[string]any{
+		"status":           status,
+		"app_id":           appID,
+		"exit_code":        res.ExitCode,
+		"stdout":           res.Stdout,
+		"stderr":           res.Stderr,
+		"stdout_truncated": res.StdoutTruncated,
+		"stderr_truncated": res.StderrTruncated,
+		"duration_ms":      res.DurationMS,
+	}
+	if res.TimedOut {
+		resp["error"] = fmt.Sprintf("command timed out after %s", timeout)
+	}
+	writeJSON(w, code, resp)
 }
 
 func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
@@ -539,48 +577,142 @@ func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 
 	switch logType {
 	case "build":
-		// Return build log lines from the store
+		// Return the lines of a build log, the latest unless deploy= names one
+		build, ok := findBuild(state, r.URL.Query().Get("deploy"))
+		if !ok {
+			writeError(w, http.StatusNotFound, "build log not found")
+			return
+		}
+		lines, err := logging.ReadBuildLog(s.cfg.Paths.LogDir, appID, build.ID)
+		if err != nil {
+			writeError(w, http.StatusNotFound, "build log not found: "+err.Error())
+			return
+		}
 		writeJSON(w, http.StatusOK, map[string]any{
 			"app_id": appID,
 			"type":   "build",
-			"lines":  state.BuildLog,
+			"deploy": build.ID,
+			"kind":   build.Kind,
+			"lines":  lines,
 		})
 	case "runtime":
-		// Tail the runtime log file
-		processName := r.URL.Query().Get("process")
-		if processName == "" {
-			processName = "web"
+		s.handleRuntimeLogs(w, r, state)
+	default:
+		writeError(w, http.StatusBadRequest, "invalid type: use 'build' or 'runtime'")
+	}
+}
+
+// findBuild looks up a build by ID, or the latest build if id is empty.
+// Only IDs recorded on the app are accepted, so id can't escape the log dir.
+func findBuild(state *app.AppState, id string) (app.Build, bool) {
+	for _, b := range state.Builds {
+		if id == "" || b.ID == id {
+			return b, true
 		}
+	}
+	return app.Build{}, false
+}
 
-		logPath := s.processLogPath(appID, processName)
-		data, err := os.ReadFile(logPath)
-		if err != nil {
-			writeError(w, http.StatusNotFound, "log file not found: "+err.Error())
+// runtimeProcess returns the process named by the request's process parameter
+// (web by default), and whether it is one of the app's processes. Only those
+// are accepted, so the name can't be used to read another app's log.
+func (s *Server) runtimeProcess(r *http.Request, state *app.AppState) (string, bool) {
+	name := r.URL.Query().Get("process")
+	if name == "" {
+		name = "web"
+	}
+	return name, s.sup.HasProcess(state, name)
+}
+
+// handleRuntimeLogs returns the last N lines (default 100) of a process log,
+// or of all processes merged in time order with process=*. since and until
+// take an RFC 3339 time or a duration ago (e.g. 15m); grep takes a regexp
+// matched against the line text.
+func (s *Server) handleRuntimeLogs(w http.ResponseWriter, r *http.Request, state *app.AppState) {
+	appID := state.AppID
+	q := r.URL.Query()
+	processName, known := s.runtimeProcess(r, state)
+	if processName != "*" && !known {
+		writeError(w, http.StatusNotFound, "unknown process "+processName)
+		return
+	}
+
+	query := logwriter.Query{Limit: 100}
+	if qn := q.Get("lines"); qn != "" {
+		if v, err := strconv.Atoi(qn); err == nil && v > 0 {
+			query.Limit = v
+		}
+	}
+	var err error
+	if query.Since, err = parseLogTime(q.Get("since")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid since: "+err.Error())
+		return
+	}
+	if query.Until, err = parseLogTime(q.Get("until")); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid until: "+err.Error())
+		return
+	}
+	if g := q.Get("grep"); g != "" {
+		if query.Grep, err = regexp.Compile(g); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid grep: "+err.Error())
 			return
 		}
+	}
+
+	paths := map[string]string{processName: s.sup.LogPath(appID, processName)}
+	if processName == "*" {
+		paths = s.sup.ProcessLogs(appID)
+	}
 
-		// Return last N lines (default 100)
-		lines := strings.Split(string(data), "\n")
-		n := 100
-		if qn := r.URL.Query().Get("lines"); qn != "" {
-			if v, err := strconv.Atoi(qn); err == nil && v > 0 {
-				n = v
+	var lines []logwriter.Line
+	found := false
+	for name, path := range paths {
+		read, err := logwriter.Read(path, query)
+		if err != nil {
+			continue
+		}
+		found = true
+		for i := range read {
+			if read[i].Process == "" {
+				read[i].Process = name
 			}
 		}
-		if n > len(lines) {
-			n = len(lines)
+		lines = append(lines, read...)
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "log file not found for process "+processName)
+		return
+	}
+
+	if len(paths) > 1 {
+		sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time.Before(lines[j].Time) })
+		if len(lines) > query.Limit {
+			lines = lines[len(lines)-query.Limit:]
 		}
-		tail := lines[len(lines)-n:]
+	}
 
-		writeJSON(w, http.StatusOK, map[string]any{
-			"app_id":  appID,
-			"type":    "runtime",
-			"process": processName,
-			"lines":   tail,
-		})
-	default:
-		writeError(w, http.StatusBadRequest, "invalid type: use 'build' or 'runtime'")
+	out := make([]string, len(lines))
+	for i, l := range lines {
+		out[i] = l.String()
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"app_id":  appID,
+		"type":    "runtime",
+		"process": processName,
+		"lines":   out,
+	})
+}
+
+// parseLogTime parses an RFC 3339 time or a duration before now. Empty is
+// the zero time.
+func parseLogTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return time.Now().Add(-d), nil
 	}
+	return time.Parse(time.RFC3339, v)
 }
 
 func (s *Server) handleUpdate(w http.ResponseWriter, r *http.Request) {
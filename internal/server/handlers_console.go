@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os/exec"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/audit"
+	"github.com/reviewapps-dev/rad/internal/pty"
+	"github.com/reviewapps-dev/rad/internal/rv"
+	"nhooyr.io/websocket"
+)
+
+// consoleMessage is a control message from the client (text frame) or to
+// it. Binary frames carry raw terminal input and output.
+type consoleMessage struct {
+	Type string `json:"type"`           // resize, input (client); exit (server)
+	Cols uint16 `json:"cols,omitempty"` // resize
+	Rows uint16 `json:"rows,omitempty"` // resize
+	Data string `json:"data,omitempty"` // input
+	Code *int   `json:"code,omitempty"` // exit
+}
+
+// handleConsole runs an interactive command (default `bin/rails console`) on
+// a PTY in the app's release directory with the app's env, and connects it
+// to a WebSocket. The session ends when the command exits, the client
+// disconnects, or nothing is typed or printed for console.idle_minutes.
+func (s *Server) handleConsole(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	state, err := s.store.Get(appID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	switch state.Status {
+	case app.StatusRunning, app.StatusStopped, app.StatusSleeping:
+	default:
+		writeError(w, http.StatusConflict, "app is "+string(state.Status))
+		return
+	}
+
+	q := r.URL.Query()
+	command := q.Get("command")
+	if command == "" {
+		command = s.cfg.Console.Command
+	}
+	cols, rows := uint16(80), uint16(24)
+	if v, err := strconv.ParseUint(q.Get("cols"), 10, 16); err == nil && v > 0 {
+		cols = uint16(v)
+	}
+	if v, err := strconv.ParseUint(q.Get("rows"), 10, 16); err == nil && v > 0 {
+		rows = uint16(v)
+	}
+
+	env := append(s.sup.LoadEnv(state), "TERM=xterm-256color")
+	cmd := rv.ExecInDir(state.RepoDir(), state.RubyVersion, env, command)
+	master, err := pty.Start(cmd, cols, rows)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "start console: "+err.Error())
+		return
+	}
+	defer master.Close()
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
+		InsecureSkipVerify: true, // allow any origin (token auth is sufficient)
+	})
+	if err != nil {
+		log.Printf("console: accept failed for %s: %v", appID, err)
+		killSession(cmd)
+		cmd.Wait()
+		return
+	}
+	defer conn.CloseNow()
+
+	session := newSessionID()
+	started := time.Now()
+	s.audit.Record(audit.Event{
+		Action:  "console.start",
+		AppID:   appID,
+		Session: session,
+		Remote:  r.RemoteAddr,
+		Token:   tokenFrom(r).Name,
+		Command: command,
+	})
+	log.Printf("console: %s started %q for %s", session, command, appID)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	var lastActive atomic.Int64
+	touch := func() { lastActive.Store(time.Now().UnixNano()) }
+	touch()
+
+	// PTY → client
+	outputDone := make(chan struct{})
+	go func() {
+		defer close(outputDone)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := master.Read(buf)
+			if n > 0 {
+				touch()
+				if werr := conn.Write(ctx, websocket.MessageBinary, buf[:n]); werr != nil {
+					cancel()
+					return
+				}
+			}
+			if err != nil {
+				// EIO once the command and everything on the terminal exited
+				return
+			}
+		}
+	}()
+
+	// Client → PTY
+	go func() {
+		defer cancel()
+		for {
+			typ, data, err := conn.Read(ctx)
+			if err != nil {
+				return
+			}
+			touch()
+			if typ == websocket.MessageBinary {
+				master.Write(data)
+				continue
+			}
+			var msg consoleMessage
+			if json.Unmarshal(data, &msg) != nil {
+				continue
+			}
+			switch msg.Type {
+			case "input":
+				master.Write([]byte(msg.Data))
+			case "resize":
+				if msg.Cols > 0 && msg.Rows > 0 {
+					pty.Resize(master, msg.Cols, msg.Rows)
+				}
+			}
+		}
+	}()
+
+	// Idle timeout
+	idle := time.Duration(s.cfg.Console.IdleMinutes) * time.Minute
+	if idle > 0 {
+		go func() {
+			ticker := time.NewTicker(time.Minute)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if time.Since(time.Unix(0, lastActive.Load())) > idle {
+						log.Printf("console: %s idle for %s, closing", session, idle)
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	reason := "exited"
+	select {
+	case <-outputDone:
+	case <-ctx.Done():
+		reason = "closed"
+		killSession(cmd)
+	}
+	waitErr := cmd.Wait()
+	code := exitCode(waitErr)
+
+	s.audit.Record(audit.Event{
+		Action:   "console.end",
+		AppID:    appID,
+		Session:  session,
+		Remote:   r.RemoteAddr,
+		Token:    tokenFrom(r).Name,
+		Command:  command,
+		ExitCode: &code,
+		Duration: time.Since(started).Round(time.Second).String(),
+		Detail:   reason,
+	})
+	log.Printf("console: %s %s (exit=%d)", session, reason, code)
+
+	if data, err := json.Marshal(consoleMessage{Type: "exit", Code: &code}); err == nil {
+		conn.Write(context.Background(), websocket.MessageText, data)
+	}
+	conn.Close(websocket.StatusNormalClosure, reason)
+}
+
+// killSession kills the console command and everything it started. The
+// command leads its own session, so its process group ID is its PID.
+func killSession(cmd *exec.Cmd) {
+	if cmd.Process != nil {
+		syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
+
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+func newSessionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/reviewapps-dev/rad/internal/diskusage"
+)
+
+type appDisk struct {
+	AppID string `json:"app_id"`
+	diskusage.Usage
+}
+
+func (s *Server) handleAppDisk(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	state, err := s.store.Get(appID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, appDisk{
+		AppID: appID,
+		Usage: diskusage.ForApp(s.cfg, state),
+	})
+}
+
+func (s *Server) handleDisk(w http.ResponseWriter, r *http.Request) {
+	if token := tokenFrom(r); !token.Unrestricted() {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("token %s is limited to some apps", token.Name))
+		return
+	}
+	total := diskusage.Usage{Categories: make(map[string]int64)}
+	apps := make([]appDisk, 0, s.store.Count())
+
+	owned := make(map[string]bool)
+	for _, state := range s.store.List() {
+		owned[filepath.Join(s.cfg.Paths.LogDir, state.AppID)] = true
+		u := diskusage.ForApp(s.cfg, state)
+		total.Merge(u)
+		apps = append(apps, appDisk{AppID: state.AppID, Usage: u})
+		for _, f := range diskusage.LogFiles(s.cfg, state.AppID) {
+			owned[f] = true
+		}
+	}
+	sort.Slice(apps, func(i, j int) bool { return apps[i].Total > apps[j].Total })
+
+	// Logs in LogDir that no app owns (e.g. left by apps torn down before
+	// teardown removed logs)
+	var orphanedLogs int64
+	if entries, err := os.ReadDir(s.cfg.Paths.LogDir); err == nil {
+		for _, e := range entries {
+			path := filepath.Join(s.cfg.Paths.LogDir, e.Name())
+			if owned[path] || path == filepath.Clean(s.cfg.Paths.AuditLog) {
+				continue
+			}
+			if e.IsDir() {
+				orphanedLogs += diskusage.DirSize(path)
+				continue
+			}
+			if !strings.Contains(e.Name(), ".log") {
+				continue
+			}
+			if info, err := e.Info(); err == nil {
+				orphanedLogs += info.Size()
+			}
+		}
+	}
+
+	resp := map[string]any{
+		"total_bytes":         total.Total,
+		"categories":          total.Categories,
+		"orphaned_logs_bytes": orphanedLogs,
+		"apps":                apps,
+	}
+
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(s.cfg.Paths.AppsDir, &st); err == nil {
+		resp["filesystem"] = map[string]uint64{
+			"size_bytes": st.Blocks * uint64(st.Bsize),
+			"free_bytes": st.Bavail * uint64(st.Bsize),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/execjob"
+	"github.com/reviewapps-dev/rad/internal/rv"
+)
+
+// startExecJob runs an exec request in the background and returns the job.
+// Async jobs have no timeout unless the request sets one.
+func (s *Server) startExecJob(w http.ResponseWriter, state *app.AppState, req ExecRequest) {
+	cmd := rv.ExecInDir(state.RepoDir(), state.RubyVersion, s.sup.LoadEnv(state), req.Command)
+	timeout := time.Duration(req.Timeout) * time.Second
+	job, err := s.jobs.Start(state.AppID, req.Command, cmd, timeout)
+	if errors.Is(err, execjob.ErrTooManyJobs) {
+		writeError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	log.Printf("exec: job %s running %q for %s (timeout=%s)", job.ID, req.Command, state.AppID, timeout)
+	writeJSON(w, http.StatusAccepted, job.Info())
+}
+
+func (s *Server) handleListExecJobs(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	if _, err := s.store.Get(appID); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	jobs := []execjob.Info{}
+	for _, j := range s.jobs.List(appID) {
+		jobs = append(jobs, j.Info())
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"app_id": appID,
+		"jobs":   jobs,
+	})
+}
+
+// handleGetExecJob returns a job's status and its output. ?after=N returns
+// only lines after line N, for polling; last is the N to pass next time.
+func (s *Server) handleGetExecJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.findJob(w, r)
+	if !ok {
+		return
+	}
+	from := 0
+	if n, err := strconv.Atoi(r.URL.Query().Get("after")); err == nil {
+		from = n + 1
+	}
+	lines, next, _ := job.Lines(from)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"job":    job.Info(),
+		"output": lines,
+		"last":   next - 1,
+	})
+}
+
+// handleKillExecJob stops a running job and everything it started.
+func (s *Server) handleKillExecJob(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.findJob(w, r)
+	if !ok {
+		return
+	}
+	job.Kill()
+	writeJSON(w, http.StatusOK, job.Info())
+}
+
+// handleExecJobEvents streams a job's output as Server-Sent Events until it
+// exits. Each line is an "output" event whose id is its line number, so a
+// reconnecting client resumes via Last-Event-ID. The stream ends with an
+// "exit" event carrying the job's final status and exit code.
+func (s *Server) handleExecJobEvents(w http.ResponseWriter, r *http.Request) {
+	job, ok := s.findJob(w, r)
+	if !ok {
+		return
+	}
+	from := 0
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	if n, err := strconv.Atoi(lastID); err == nil {
+		from = n + 1
+	}
+
+	ctx, sse, cancel := openSSE(w, r)
+	defer cancel()
+	if sse == nil {
+		return
+	}
+	streamJob(ctx, job, from, func(n int, line execjob.Line) bool {
+		data, _ := json.Marshal(line)
+		return sse.send(strconv.Itoa(n), "output", string(data))
+	})
+	if ctx.Err() != nil {
+		return
+	}
+	data, _ := json.Marshal(job.Info())
+	sse.send("", "exit", string(data))
+}
+
+// streamJob calls fn with every line of the job's output from line number
+// from on, until the job exits, fn returns false or ctx ends.
+func streamJob(ctx context.Context, job *execjob.Job, from int, fn func(n int, line execjob.Line) bool) {
+	for {
+		lines, next, changed := job.Lines(from)
+		for i, line := range lines {
+			if !fn(next-len(lines)+i, line) {
+				return
+			}
+		}
+		from = next
+		select {
+		case <-job.Done():
+			// Pick up output that arrived with the exit
+			if lines, next, _ := job.Lines(from); len(lines) > 0 {
+				for i, line := range lines {
+					if !fn(next-len(lines)+i, line) {
+						return
+					}
+				}
+			}
+			return
+		case <-changed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Server) findJob(w http.ResponseWriter, r *http.Request) (*execjob.Job, bool) {
+	appID := r.PathValue("app_id")
+	if _, err := s.store.Get(appID); err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return nil, false
+	}
+	job, ok := s.jobs.Get(appID, r.PathValue("job_id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "job not found")
+		return nil, false
+	}
+	return job, true
+}
@@ -0,0 +1,251 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/process"
+	"github.com/reviewapps-dev/rad/internal/supervisor"
+)
+
+type processStatus struct {
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	Status     string    `json:"status"` // "running", "stopped", or "dead"
+	PID        int       `json:"pid"`
+	Port       int       `json:"port,omitempty"`
+	Command    string    `json:"command,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	Uptime     float64   `json:"uptime"`
+	Restarts   int       `json:"restarts"`
+	MemoryMB   int       `json:"memory_mb"`
+	CPUPercent float64   `json:"cpu_percent"`
+	LogPath    string    `json:"log_path"`
+}
+
+func (s *Server) handleListProcesses(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	state, err := s.store.Get(appID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	// Report every declared instance, including ones that were never started
+	names := s.sup.Instances(state)
+	for name := range state.Processes {
+		if _, ok := names[name]; !ok {
+			names[name] = ""
+		}
+	}
+
+	procs := make([]processStatus, 0, len(names))
+	for _, name := range supervisor.SortNames(names) {
+		info := state.Processes[name]
+		ps := processStatus{
+			Name:     name,
+			Type:     app.ProcessType(name),
+			Status:   "stopped",
+			PID:      info.PID,
+			Port:     info.Port,
+			Command:  names[name],
+			Restarts: info.Restarts,
+			LogPath:  s.sup.LogPath(appID, name),
+		}
+		if info.PID > 0 {
+			if process.IsAlive(info.PID) {
+				ps.Status = "running"
+				ps.MemoryMB, ps.CPUPercent = getProcessStats(info.PID)
+				if !info.StartedAt.IsZero() {
+					ps.StartedAt = info.StartedAt
+					ps.Uptime = time.Since(info.StartedAt).Seconds()
+				}
+			} else {
+				ps.Status = "dead"
+			}
+		}
+		procs = append(procs, ps)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"app_id":    appID,
+		"processes": procs,
+	})
+}
+
+func (s *Server) handleProcessStart(w http.ResponseWriter, r *http.Request) {
+	state, name, ok := s.lookupProcess(w, r)
+	if !ok {
+		return
+	}
+
+	if process.IsAlive(state.Processes[name].PID) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("process %q is already running", name))
+		return
+	}
+
+	proc, err := s.sup.Start(state, name)
+	if err != nil {
+		log.Printf("process: start %s/%s: %v", state.AppID, name, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":  "started",
+		"app_id":  state.AppID,
+		"process": name,
+		"pid":     proc.PID,
+	})
+}
+
+func (s *Server) handleProcessStop(w http.ResponseWriter, r *http.Request) {
+	state, name, ok := s.lookupProcess(w, r)
+	if !ok {
+		return
+	}
+
+	if !process.IsAlive(state.Processes[name].PID) {
+		writeError(w, http.StatusConflict, fmt.Sprintf("process %q is not running", name))
+		return
+	}
+
+	if err := s.sup.Stop(state, name); err != nil {
+		log.Printf("process: stop %s/%s: %v", state.AppID, name, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":  "stopped",
+		"app_id":  state.AppID,
+		"process": name,
+	})
+}
+
+func (s *Server) handleProcessRestart(w http.ResponseWriter, r *http.Request) {
+	state, name, ok := s.lookupProcess(w, r)
+	if !ok {
+		return
+	}
+
+	proc, err := s.sup.Restart(state, name)
+	if err != nil {
+		log.Printf("process: restart %s/%s: %v", state.AppID, name, err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":   "restarted",
+		"app_id":   state.AppID,
+		"process":  name,
+		"pid":      proc.PID,
+		"restarts": proc.Restarts,
+	})
+}
+
+func (s *Server) handleScale(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	state, err := s.store.Get(appID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	var req ScaleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if len(req.Processes) == 0 {
+		writeError(w, http.StatusBadRequest, "processes is required")
+		return
+	}
+
+	if state.Status != app.StatusRunning {
+		writeError(w, http.StatusConflict, "app is not running (status: "+string(state.Status)+")")
+		return
+	}
+
+	// Validate everything up front so a bad entry doesn't leave a half-applied scale
+	commands := s.sup.Commands(state)
+	for procType, count := range req.Processes {
+		if _, ok := commands[procType]; !ok {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("process %q not found", procType))
+			return
+		}
+		if err := app.ValidateScale(procType, count); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	for procType, count := range req.Processes {
+		log.Printf("scale: %s/%s → %d", appID, procType, count)
+		if err := s.sup.Scale(state, procType, count); err != nil {
+			writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+
+	scale := make(map[string]int, len(commands))
+	for procType := range commands {
+		scale[procType] = state.ScaleFor(procType)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status": "scaled",
+		"app_id": appID,
+		"scale":  scale,
+	})
+}
+
+// lookupProcess resolves the app and process name from the request path.
+// Writes an error response and returns ok=false if either doesn't exist or
+// the app isn't in a state where its processes can be controlled.
+func (s *Server) lookupProcess(w http.ResponseWriter, r *http.Request) (*app.AppState, string, bool) {
+	appID := r.PathValue("app_id")
+	name := r.PathValue("name")
+
+	state, err := s.store.Get(appID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return nil, "", false
+	}
+
+	_, declared := s.sup.Instances(state)[name]
+	_, tracked := state.Processes[name]
+	if !declared && !tracked {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("process %q not found", name))
+		return nil, "", false
+	}
+
+	if state.Status != app.StatusRunning {
+		writeError(w, http.StatusConflict, "app is not running (status: "+string(state.Status)+")")
+		return nil, "", false
+	}
+
+	return state, name, true
+}
+
+// getProcessStats returns the RSS memory in MB and CPU usage percent for a given PID.
+func getProcessStats(pid int) (int, float64) {
+	out, err := exec.Command("ps", "-o", "rss=,%cpu=", "-p", fmt.Sprintf("%d", pid)).Output()
+	if err != nil {
+		return 0, 0
+	}
+	// ps returns RSS in kilobytes
+	var kb int
+	var cpu float64
+	if _, err := fmt.Sscanf(strings.TrimSpace(string(out)), "%d %f", &kb, &cpu); err != nil {
+		return 0, 0
+	}
+	return kb / 1024, cpu
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/logging"
+	"github.com/reviewapps-dev/rad/internal/logstream"
+	"github.com/reviewapps-dev/rad/internal/logwriter"
+)
+
+// sseWriter writes Server-Sent Events. Safe for concurrent use.
+type sseWriter struct {
+	mu sync.Mutex
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+func (s *sseWriter) send(id, event, data string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var b strings.Builder
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", id)
+	}
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	if _, err := s.w.Write([]byte(b.String())); err != nil {
+		return false
+	}
+	return s.rc.Flush() == nil
+}
+
+func (s *sseWriter) ping() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write([]byte(": ping\n\n")); err != nil {
+		return false
+	}
+	return s.rc.Flush() == nil
+}
+
+// openSSE starts an event stream response and keeps it alive with pings.
+// The returned context ends when the client goes away. sse is nil if the
+// client is already gone.
+func openSSE(w http.ResponseWriter, r *http.Request) (context.Context, *sseWriter, context.CancelFunc) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // don't let nginx buffer the stream
+	w.WriteHeader(http.StatusOK)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	sse := &sseWriter{w: w, rc: http.NewResponseController(w)}
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if !sse.ping() {
+		return ctx, nil, cancel
+	}
+
+	// Keep idle connections alive through proxies
+	go func() {
+		ticker := time.NewTicker(15 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if !sse.ping() {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+	return ctx, sse, cancel
+}
+
+// handleLogEvents streams logs as Server-Sent Events, for clients behind
+// proxies that block WebSocket upgrades. Same modes and backlog as
+// handleLogStream. Every line carries an id, so a reconnecting EventSource
+// resumes after the last line it saw via Last-Event-ID.
+func (s *Server) handleLogEvents(w http.ResponseWriter, r *http.Request) {
+	appID := r.PathValue("app_id")
+	state, err := s.store.Get(appID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	logType := r.URL.Query().Get("type")
+	if logType == "" {
+		logType = "build"
+	}
+	if logType != "build" && logType != "runtime" {
+		writeError(w, http.StatusBadRequest, "invalid type: use 'build' or 'runtime'")
+		return
+	}
+	processName, known := s.runtimeProcess(r, state)
+	if logType == "runtime" && !known {
+		writeError(w, http.StatusNotFound, "unknown process "+processName)
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+
+	ctx, sse, cancel := openSSE(w, r)
+	defer cancel()
+	if sse == nil {
+		return
+	}
+
+	switch logType {
+	case "build":
+		s.sseBuildLogs(ctx, sse, state, lastID)
+	case "runtime":
+		s.sseRuntimeLogs(ctx, sse, appID, processName, lastID)
+	}
+}
+
+// sseBuildLogs sends a build log and follows it until the deploy finishes.
+// Event ids are {build_id}/{offset}.
+func (s *Server) sseBuildLogs(ctx context.Context, sse *sseWriter, state *app.AppState, lastID string) {
+	appID := state.AppID
+	var offset int64
+	buildID := ""
+	if id, off, ok := strings.Cut(lastID, "/"); ok {
+		if n, err := strconv.ParseInt(off, 10, 64); err == nil {
+			if _, found := findBuild(state, id); found {
+				buildID, offset = id, n
+			}
+		}
+	}
+	if buildID == "" {
+		build, ok := findBuild(state, "")
+		if !ok {
+			sse.send("", "done", "no build log")
+			return
+		}
+		buildID = build.ID
+	}
+
+	// The hub closes subscriptions when the deploy finishes; that's when to
+	// stop. Subscribe before checking the status so the close isn't missed.
+	followCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	ch, unsub := s.hub.Subscribe(appID, s.hub.Last(appID))
+	defer unsub()
+	if latest, _ := findBuild(state, ""); latest.ID == buildID && buildInProgress(state.Status) {
+		go func() {
+			defer stop()
+			for {
+				select {
+				case <-followCtx.Done():
+					return
+				case _, ok := <-ch:
+					if !ok {
+						return
+					}
+				}
+			}
+		}()
+	} else {
+		stop()
+	}
+
+	path := logging.BuildLogPath(s.cfg.Paths.LogDir, appID, buildID)
+	err := logstream.Follow(followCtx, path, logstream.Position{Offset: offset}, 500*time.Millisecond, func(line string, pos logstream.Position) bool {
+		return sse.send(buildID+"/"+strconv.FormatInt(pos.Offset, 10), "", line)
+	})
+	if err != nil {
+		sse.send("", "done", "build log not found")
+		return
+	}
+	if ctx.Err() == nil {
+		sse.send("", "done", "")
+	}
+}
+
+// sseRuntimeLogs sends the last 100 lines of a process log, then follows it.
+// Event ids are {inode}-{offset}, which stay valid across rotation.
+func (s *Server) sseRuntimeLogs(ctx context.Context, sse *sseWriter, appID, processName, lastID string) {
+	path := s.sup.LogPath(appID, processName)
+
+	from, resume := logstream.ParsePosition(lastID)
+	if !resume {
+		// Wait for the file to exist (it may not be created yet)
+		for {
+			end, err := logstream.EndPosition(path)
+			if err == nil {
+				from = end
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(500 * time.Millisecond):
+			}
+		}
+
+		// Only the last backlog line carries an id: resuming from it
+		// continues right after the backlog. Lines written since from are
+		// left to Follow, so none are sent twice.
+		backlog, _ := logwriter.TailBefore(path, 100, from.Offset)
+		for i, line := range backlog {
+			id := ""
+			if i == len(backlog)-1 {
+				id = from.String()
+			}
+			if !sse.send(id, "", line) {
+				return
+			}
+		}
+	}
+
+	logstream.Follow(ctx, path, from, 500*time.Millisecond, func(line string, pos logstream.Position) bool {
+		return sse.send(pos.String(), "", line)
+	})
+}
+
+// buildInProgress reports whether a deploy or teardown is still writing its
+// build log.
+func buildInProgress(status app.Status) bool {
+	switch status {
+	case app.StatusRunning, app.StatusFailed, app.StatusStopped, app.StatusSleeping:
+		return false
+	}
+	return true
+}
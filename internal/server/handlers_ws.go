@@ -2,11 +2,16 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/logging"
 	"github.com/reviewapps-dev/rad/internal/logstream"
+	"github.com/reviewapps-dev/rad/internal/logwriter"
 	"nhooyr.io/websocket"
 )
 
@@ -18,6 +23,16 @@ func (s *Server) handleLogStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	logType := r.URL.Query().Get("type")
+	if logType == "" {
+		logType = "build"
+	}
+	processName, known := s.runtimeProcess(r, state)
+	if logType == "runtime" && !known {
+		writeError(w, http.StatusNotFound, "unknown process "+processName)
+		return
+	}
+
 	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
 		InsecureSkipVerify: true, // allow any origin (token auth is sufficient)
 	})
@@ -29,56 +44,59 @@ func (s *Server) handleLogStream(w http.ResponseWriter, r *http.Request) {
 
 	ctx := conn.CloseRead(r.Context())
 
-	logType := r.URL.Query().Get("type")
-	if logType == "" {
-		logType = "build"
-	}
+	// after=<seq> resumes build lines after that hub sequence number
+	after, err := strconv.ParseUint(r.URL.Query().Get("after"), 10, 64)
+	resume := err == nil
 
 	switch logType {
 	case "build":
-		s.streamBuildLogs(ctx, conn, appID, state)
+		s.streamBuildLogs(ctx, conn, appID, state, after, resume)
 	case "runtime":
-		processName := r.URL.Query().Get("process")
-		if processName == "" {
-			processName = "web"
-		}
 		s.streamRuntimeLogs(ctx, conn, appID, processName)
+	case "all":
+		s.streamAll(ctx, conn, appID, after, resume)
 	default:
-		conn.Close(websocket.StatusPolicyViolation, "invalid type: use 'build' or 'runtime'")
+		conn.Close(websocket.StatusPolicyViolation, "invalid type: use 'build', 'runtime' or 'all'")
 		return
 	}
 
 	conn.Close(websocket.StatusNormalClosure, "done")
 }
 
-func (s *Server) streamBuildLogs(ctx context.Context, conn *websocket.Conn, appID string, state *app.AppState) {
-	// Subscribe first so we don't miss lines published between reading backlog and subscribing
-	ch, unsub := s.hub.Subscribe(appID)
-	defer unsub()
-
-	// Send existing build log as backlog
-	for _, line := range state.BuildLog {
-		if err := conn.Write(ctx, websocket.MessageText, []byte(line)); err != nil {
-			return
+// streamBuildLogs sends the log of the deploy in progress, replayed from the
+// hub so no line is sent twice, then follows it until the deploy finishes.
+// With after= it resumes after that sequence number instead. When no deploy
+// is running it sends the latest build log from disk and returns.
+func (s *Server) streamBuildLogs(ctx context.Context, conn *websocket.Conn, appID string, state *app.AppState, after uint64, resume bool) {
+	if !resume && !buildInProgress(state.Status) {
+		if build, ok := findBuild(state, ""); ok {
+			lines, _ := logging.ReadBuildLog(s.cfg.Paths.LogDir, appID, build.ID)
+			for _, line := range lines {
+				if err := conn.Write(ctx, websocket.MessageText, []byte(line)); err != nil {
+					return
+				}
+			}
 		}
+		return
 	}
 
-	// If the deploy is already done (not queued/building/cloning/starting), close immediately
-	if state.Status == app.StatusRunning || state.Status == app.StatusFailed || state.Status == app.StatusStopped {
-		return
+	if !resume {
+		after = s.hub.BuildStart(appID)
 	}
+	ch, unsub := s.hub.Subscribe(appID, after)
+	defer unsub()
 
-	// Stream new lines from the hub until deploy finishes or client disconnects
+	// Stream lines from the hub until the deploy finishes or the client disconnects
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case line, ok := <-ch:
+		case entry, ok := <-ch:
 			if !ok {
 				// Channel closed — deploy finished
 				return
 			}
-			if err := conn.Write(ctx, websocket.MessageText, []byte(line)); err != nil {
+			if err := conn.Write(ctx, websocket.MessageText, []byte(entry.Line)); err != nil {
 				return
 			}
 		}
@@ -86,7 +104,7 @@ func (s *Server) streamBuildLogs(ctx context.Context, conn *websocket.Conn, appI
 }
 
 func (s *Server) streamRuntimeLogs(ctx context.Context, conn *websocket.Conn, appID, processName string) {
-	logPath := s.processLogPath(appID, processName)
+	logPath := s.sup.LogPath(appID, processName)
 	tailer := logstream.NewTailer(logPath, 100)
 	ch := tailer.Start(ctx)
 
@@ -104,3 +122,118 @@ func (s *Server) streamRuntimeLogs(ctx context.Context, conn *websocket.Conn, ap
 		}
 	}
 }
+
+// streamFrame is one message of a type=all stream.
+type streamFrame struct {
+	Source string    `json:"source"` // build, event, or a process name (web, worker.1)
+	Time   time.Time `json:"ts"`
+	Line   string    `json:"line"`
+	Seq    uint64    `json:"seq,omitempty"`    // hub sequence number, for build lines
+	Stream string    `json:"stream,omitempty"` // stdout or stderr, for process lines
+	Event  string    `json:"event,omitempty"`  // event type, for source=event
+}
+
+// streamAll multiplexes build lines, every process log and lifecycle events
+// for an app into one stream of JSON frames. It runs until the client
+// disconnects. Process logs that appear later (after a deploy or scale) are
+// picked up as they show up.
+func (s *Server) streamAll(ctx context.Context, conn *websocket.Conn, appID string, after uint64, resume bool) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	frames := make(chan streamFrame, 256)
+	send := func(f streamFrame) {
+		select {
+		case frames <- f:
+		case <-ctx.Done():
+		}
+	}
+
+	events, unsubEvents := s.events.Subscribe(appID)
+	defer unsubEvents()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev := <-events:
+				send(streamFrame{Source: "event", Time: ev.Time, Line: ev.Message, Event: ev.Type})
+			}
+		}
+	}()
+
+	go s.fanInBuild(ctx, appID, after, resume, send)
+	go s.fanInProcesses(ctx, appID, send)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case f := <-frames:
+			data, err := json.Marshal(f)
+			if err != nil {
+				continue
+			}
+			if err := conn.Write(ctx, websocket.MessageText, data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// fanInBuild sends build log lines across deploys, starting with the deploy
+// in progress (or after the given sequence number when resuming).
+func (s *Server) fanInBuild(ctx context.Context, appID string, after uint64, resume bool, send func(streamFrame)) {
+	if !resume {
+		after = s.hub.BuildStart(appID)
+	}
+	ch, unsub := s.hub.Follow(appID, after)
+	defer unsub()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case entry, ok := <-ch:
+			if !ok {
+				return
+			}
+			send(streamFrame{Source: "build", Time: time.Now(), Line: entry.Line, Seq: entry.Seq})
+		}
+	}
+}
+
+// fanInProcesses tails every process log of the app, starting a tailer for
+// each new log file it finds.
+func (s *Server) fanInProcesses(ctx context.Context, appID string, send func(streamFrame)) {
+	tailing := make(map[string]bool)
+	scan := func() {
+		for name, path := range s.sup.ProcessLogs(appID) {
+			if tailing[name] {
+				continue
+			}
+			tailing[name] = true
+			go func(name, path string) {
+				for line := range logstream.NewTailer(path, 20).Start(ctx) {
+					l := logwriter.ParseLine(line)
+					if l.Time.IsZero() {
+						l.Time = time.Now()
+					}
+					send(streamFrame{Source: name, Time: l.Time, Line: l.Text, Stream: l.Stream})
+				}
+			}(name, path)
+		}
+	}
+
+	scan()
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			scan()
+		}
+	}
+}
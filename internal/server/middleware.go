@@ -2,14 +2,46 @@ package server
 
 import (
 	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/reviewapps-dev/rad/internal/auth"
 )
 
+type ctxKey int
+
+const requestInfoKey ctxKey = iota
+
+// requestInfo carries what the auth middlewares learn about a request back
+// out to loggingMiddleware.
+type requestInfo struct {
+	token *auth.Token
+	scope string // the scope the request was authorized under
+}
+
+func infoFrom(r *http.Request) *requestInfo {
+	if info, ok := r.Context().Value(requestInfoKey).(*requestInfo); ok {
+		return info
+	}
+	return &requestInfo{}
+}
+
+// tokenFrom returns the token that authenticated the request.
+func tokenFrom(r *http.Request) *auth.Token {
+	if t := infoFrom(r).token; t != nil {
+		return t
+	}
+	return &auth.Token{Name: "none"}
+}
+
 func (s *Server) authMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		header := r.Header.Get("Authorization")
@@ -18,46 +50,147 @@ func (s *Server) authMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
-		token := strings.TrimPrefix(header, "Bearer ")
-		if token == header {
+		secret := strings.TrimPrefix(header, "Bearer ")
+		if secret == header {
 			writeError(w, http.StatusUnauthorized, "invalid authorization format")
 			return
 		}
 
-		if token != s.cfg.Auth.Token {
+		token, ok := s.tokens.Lookup(secret)
+		if !ok {
 			writeError(w, http.StatusUnauthorized, "invalid token")
 			return
 		}
+		infoFrom(r).token = token
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientCertMiddleware requires a verified client certificate when a client
+// CA is configured. The TLS handshake already rejected certificates not
+// signed by it; this rejects connections that presented none.
+func (s *Server) clientCertMiddleware(next http.Handler) http.Handler {
+	if s.certs == nil || !s.certs.RequiresClientCerts() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			writeError(w, http.StatusUnauthorized, "client certificate required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// maxSignedBody caps the request bodies read to check signatures.
+const maxSignedBody = 10 << 20
+
+// signatureMiddleware checks HMAC request signatures when a request signing
+// key is configured. Unsigned requests pass unless signatures are required.
+// The body is read to hash it and handed on unchanged.
+func (s *Server) signatureMiddleware(next http.Handler) http.Handler {
+	key := s.cfg.Auth.RequestSigningKey
+	if key == "" {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.IsSigned(r) {
+			if s.cfg.Auth.RequireSignedRequests {
+				writeError(w, http.StatusUnauthorized, "request signature required")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
 
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBody))
+		if err != nil {
+			writeError(w, http.StatusRequestEntityTooLarge, "read body: "+err.Error())
+			return
+		}
+		if err := auth.VerifyRequest(r, key, body, s.nonces, time.Now()); err != nil {
+			writeError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
 		next.ServeHTTP(w, r)
 	})
 }
 
-// streamAuthMiddleware accepts either the main Bearer token (via header) or
-// the read-only stream token (via ?token= query param). Browser WebSocket APIs
-// cannot set custom headers, so the query param is the primary auth mechanism
-// for streaming clients.
+// require wraps a handler on the authed mux so it only runs for tokens
+// holding scope. Routes with an {app_id} also need a token allowed for that
+// app; handlers that take the app ID from the body check it themselves.
+func (s *Server) require(scope string, h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := tokenFrom(r)
+		if !token.Has(scope) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("token %s lacks scope %s", token.Name, scope))
+			return
+		}
+		if appID := r.PathValue("app_id"); appID != "" && !token.AllowsApp(appID) {
+			writeError(w, http.StatusForbidden, fmt.Sprintf("token %s is not allowed for app %s", token.Name, appID))
+			return
+		}
+		infoFrom(r).scope = scope
+		h(w, r)
+	})
+}
+
+// streamAuthMiddleware accepts any token with the read scope for the app,
+// via the Authorization header or the ?token= query param, and via ?token=
+// the read-only stream token or a signed stream token for this app and
+// stream type. Browser WebSocket APIs cannot set custom headers, so the
+// query param is the primary auth mechanism for streaming clients.
 func (s *Server) streamAuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Try Bearer header first (curl / API usage)
+		appID := r.PathValue("app_id")
+		info := infoFrom(r)
+
+		// Try Bearer header first (curl / API usage), then ?token= (browser WebSocket)
+		secrets := []string{r.URL.Query().Get("token")}
 		if header := r.Header.Get("Authorization"); header != "" {
-			token := strings.TrimPrefix(header, "Bearer ")
-			if token != header && token == s.cfg.Auth.Token {
+			if secret := strings.TrimPrefix(header, "Bearer "); secret != header {
+				secrets = []string{secret, secrets[0]}
+			}
+		}
+		for _, secret := range secrets {
+			if token, ok := s.tokens.Lookup(secret); ok && token.Has(auth.ScopeRead) && token.AllowsApp(appID) {
+				info.token, info.scope = token, auth.ScopeRead
 				next.ServeHTTP(w, r)
 				return
 			}
 		}
 
-		// Try ?token= query param (browser WebSocket)
-		if qToken := r.URL.Query().Get("token"); qToken != "" {
-			if qToken == s.cfg.Auth.Token {
-				next.ServeHTTP(w, r)
+		qToken := secrets[len(secrets)-1]
+		if auth.IsStreamToken(qToken) {
+			claims, err := auth.VerifyStreamToken(s.cfg.Auth.StreamSigningKey, qToken, time.Now())
+			if err != nil {
+				writeError(w, http.StatusUnauthorized, err.Error())
 				return
 			}
-			if s.cfg.Auth.StreamToken != "" && qToken == s.cfg.Auth.StreamToken {
-				next.ServeHTTP(w, r)
+			logType := r.URL.Query().Get("type")
+			if logType == "" {
+				logType = "build"
+			}
+			if claims.App != appID || !claims.AllowsType(logType) {
+				writeError(w, http.StatusForbidden, fmt.Sprintf("stream token is not valid for %s logs of %s", logType, appID))
 				return
 			}
+			name := "signed"
+			if claims.Subject != "" {
+				name += ":" + claims.Subject
+			}
+			info.token, info.scope = &auth.Token{Name: name, Scopes: []string{auth.ScopeRead}}, auth.ScopeRead
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if qToken != "" && s.cfg.Auth.StreamToken != "" &&
+			subtle.ConstantTimeCompare([]byte(qToken), []byte(s.cfg.Auth.StreamToken)) == 1 {
+			info.token, info.scope = &auth.Token{Name: "stream", Scopes: []string{auth.ScopeRead}}, auth.ScopeRead
+			next.ServeHTTP(w, r)
+			return
 		}
 
 		writeError(w, http.StatusUnauthorized, "invalid or missing token")
@@ -68,8 +201,16 @@ func loggingMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
 		sw := &statusWriter{ResponseWriter: w, status: 200}
-		next.ServeHTTP(sw, r)
-		log.Printf("%s %s %d %s", r.Method, r.URL.Path, sw.status, time.Since(start).Round(time.Millisecond))
+		info := &requestInfo{}
+		next.ServeHTTP(sw, r.WithContext(context.WithValue(r.Context(), requestInfoKey, info)))
+		who := ""
+		if info.token != nil {
+			who = " token=" + info.token.Name
+			if info.scope != "" {
+				who += " scope=" + info.scope
+			}
+		}
+		log.Printf("%s %s %d %s%s", r.Method, r.URL.Path, sw.status, time.Since(start).Round(time.Millisecond), who)
 	})
 }
 
@@ -95,6 +236,12 @@ func (w *statusWriter) WriteHeader(status int) {
 	w.ResponseWriter.WriteHeader(status)
 }
 
+// Unwrap lets http.ResponseController reach the underlying ResponseWriter,
+// e.g. to flush Server-Sent Events.
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
 // Hijack forwards to the underlying ResponseWriter so WebSocket upgrades work.
 func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/auth"
+	"github.com/reviewapps-dev/rad/internal/config"
+)
+
+const testSigningKey = "stream-signing-key"
+
+// newAuthTestServer returns a handler with one route per kind of auth: a
+// deploy-scoped app route and a log stream. Both answer 204 once through.
+func newAuthTestServer(t *testing.T) http.Handler {
+	t.Helper()
+	cfg := &config.Config{}
+	cfg.Auth.Token = "main-secret"
+	cfg.Auth.StreamToken = "static-stream-token"
+	cfg.Auth.StreamSigningKey = testSigningKey
+	cfg.Auth.Tokens = []config.TokenConfig{
+		{Name: "ci", Token: "ci-secret", Scopes: []string{auth.ScopeDeploy}, Apps: []string{"shop-*"}},
+		{Name: "reader", Token: "reader-secret", Scopes: []string{auth.ScopeRead}, Apps: []string{"shop-*"}},
+	}
+	tokens, err := auth.NewStore(cfg)
+	if err != nil {
+		t.Fatalf("NewStore: %v", err)
+	}
+	s := &Server{cfg: cfg, tokens: tokens}
+
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	authed := http.NewServeMux()
+	authed.Handle("POST /apps/{app_id}/restart", s.require(auth.ScopeDeploy, ok))
+	authed.Handle("DELETE /apps/{app_id}", s.require(auth.ScopeTeardown, ok))
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /apps/{app_id}/logs/stream", s.streamAuthMiddleware(http.HandlerFunc(ok)))
+	mux.Handle("/", s.authMiddleware(authed))
+	return loggingMiddleware(mux)
+}
+
+func mintStreamToken(t *testing.T, key string, claims auth.StreamClaims) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte("v1." + signed))
+	return "v1." + signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestRequireScopeAndApp(t *testing.T) {
+	h := newAuthTestServer(t)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		secret string
+		want   int
+	}{
+		{"main token", "POST", "/apps/blog-1/restart", "main-secret", http.StatusNoContent},
+		{"scoped token on allowed app", "POST", "/apps/shop-pr-1/restart", "ci-secret", http.StatusNoContent},
+		{"wrong scope", "DELETE", "/apps/shop-pr-1", "ci-secret", http.StatusForbidden},
+		{"read token cannot deploy", "POST", "/apps/shop-pr-1/restart", "reader-secret", http.StatusForbidden},
+		{"app outside patterns", "POST", "/apps/blog-1/restart", "ci-secret", http.StatusForbidden},
+		{"pattern is not a substring match", "POST", "/apps/myshop-1/restart", "ci-secret", http.StatusForbidden},
+		{"unknown token", "POST", "/apps/shop-pr-1/restart", "nope", http.StatusUnauthorized},
+		{"stream token is not an API token", "POST", "/apps/shop-pr-1/restart", "static-stream-token", http.StatusUnauthorized},
+		{"no token", "POST", "/apps/shop-pr-1/restart", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.secret != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.secret)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("%s %s = %d, want %d (%s)", tt.method, tt.path, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestStreamAuth(t *testing.T) {
+	h := newAuthTestServer(t)
+	future := time.Now().Add(time.Hour).Unix()
+	valid := auth.StreamClaims{App: "shop-pr-1", Types: []string{"build"}, Expires: future}
+
+	tests := []struct {
+		name  string
+		query string
+		token string
+		want  int
+	}{
+		{"signed token", "", mintStreamToken(t, testSigningKey, valid), http.StatusNoContent},
+		{"signed token for another app", "", mintStreamToken(t, testSigningKey, auth.StreamClaims{App: "shop-pr-2", Types: []string{"build"}, Expires: future}), http.StatusForbidden},
+		{"signed token for another stream type", "type=runtime", mintStreamToken(t, testSigningKey, valid), http.StatusForbidden},
+		{"expired signed token", "", mintStreamToken(t, testSigningKey, auth.StreamClaims{App: "shop-pr-1", Types: []string{"build"}, Expires: time.Now().Add(-time.Second).Unix()}), http.StatusUnauthorized},
+		{"token signed with another key", "", mintStreamToken(t, "other-key", valid), http.StatusUnauthorized},
+		{"static stream token", "", "static-stream-token", http.StatusNoContent},
+		{"read token for the app", "", "reader-secret", http.StatusNoContent},
+		{"deploy token without read", "", "ci-secret", http.StatusUnauthorized},
+		{"no token", "", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := "token=" + tt.token
+			if tt.query != "" {
+				q += "&" + tt.query
+			}
+			req := httptest.NewRequest("GET", "/apps/shop-pr-1/logs/stream?"+q, nil)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("got %d, want %d (%s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package server
 
+import "time"
+
 type DeployRequest struct {
 	AppID           string            `json:"app_id"`
 	RepoURL         string            `json:"repo_url"`
@@ -15,6 +17,8 @@ type DeployRequest struct {
 	Subdomain       string            `json:"subdomain"`
 	CallbackURL     string            `json:"callback_url"`
 	Hooks           *DeployHooks      `json:"hooks,omitempty"`
+	TTL             int               `json:"ttl,omitempty"`        // seconds until the app is torn down
+	ExpiresAt       *time.Time        `json:"expires_at,omitempty"` // absolute alternative to ttl
 }
 
 type DeployHooks struct {
@@ -29,7 +33,12 @@ type DeployHooks struct {
 
 type ExecRequest struct {
 	Command string `json:"command"`
-	Timeout int    `json:"timeout,omitempty"` // seconds, default 30
+	Timeout int    `json:"timeout,omitempty"` // seconds, default 30 (none when async)
+	Async   bool   `json:"async,omitempty"`   // run as a background job and return its ID
+}
+
+type ScaleRequest struct {
+	Processes map[string]int `json:"processes"` // process type → instance count
 }
 
 type UpdateRequest struct {
@@ -6,29 +6,50 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/reviewapps-dev/rad/internal/admission"
 	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/audit"
+	"github.com/reviewapps-dev/rad/internal/auth"
 	"github.com/reviewapps-dev/rad/internal/buildqueue"
 	"github.com/reviewapps-dev/rad/internal/caddy"
+	"github.com/reviewapps-dev/rad/internal/certs"
 	"github.com/reviewapps-dev/rad/internal/config"
+	"github.com/reviewapps-dev/rad/internal/execjob"
 	"github.com/reviewapps-dev/rad/internal/logstream"
 	"github.com/reviewapps-dev/rad/internal/port"
+	"github.com/reviewapps-dev/rad/internal/sleeper"
+	"github.com/reviewapps-dev/rad/internal/supervisor"
+	"github.com/reviewapps-dev/rad/internal/teardown"
 )
 
 type DeployFunc func(ctx context.Context, state *app.AppState, redeploy bool) error
 
+type RollbackFunc func(ctx context.Context, state *app.AppState) error
+
 type Server struct {
-	cfg       *config.Config
-	store     *app.Store
-	ports     *port.Allocator
-	queue     *buildqueue.Queue
-	caddy     *caddy.Manager
-	hub       *logstream.Hub
-	httpSrv   *http.Server
-	startTime time.Time
-	deployFn  DeployFunc
+	cfg        *config.Config
+	store      *app.Store
+	ports      *port.Allocator
+	queue      *buildqueue.Queue
+	caddy      *caddy.Manager
+	hub        *logstream.Hub
+	events     *logstream.Events
+	sup        *supervisor.Supervisor
+	sleeper    *sleeper.Sleeper
+	teardown   *teardown.Teardown
+	admission  *admission.Checker
+	audit      *audit.Logger
+	jobs       *execjob.Manager
+	tokens     *auth.Store
+	nonces     *auth.NonceCache
+	certs      *certs.Reloader
+	httpSrv    *http.Server
+	startTime  time.Time
+	deployFn   DeployFunc
+	rollbackFn RollbackFunc
 }
 
-func New(cfg *config.Config, store *app.Store, ports *port.Allocator, queue *buildqueue.Queue, cm *caddy.Manager, hub *logstream.Hub) *Server {
+func New(cfg *config.Config, store *app.Store, ports *port.Allocator, queue *buildqueue.Queue, cm *caddy.Manager, hub *logstream.Hub, events *logstream.Events, sup *supervisor.Supervisor, sl *sleeper.Sleeper, td *teardown.Teardown, jobs *execjob.Manager, tokens *auth.Store, tlsCerts *certs.Reloader) *Server {
 	return &Server{
 		cfg:       cfg,
 		store:     store,
@@ -36,6 +57,16 @@ func New(cfg *config.Config, store *app.Store, ports *port.Allocator, queue *bui
 		queue:     queue,
 		caddy:     cm,
 		hub:       hub,
+		events:    events,
+		sup:       sup,
+		sleeper:   sl,
+		teardown:  td,
+		admission: admission.New(cfg, store),
+		audit:     audit.New(cfg.Paths.AuditLog),
+		jobs:      jobs,
+		tokens:    tokens,
+		nonces:    auth.NewNonceCache(),
+		certs:     tlsCerts,
 		startTime: time.Now(),
 	}
 }
@@ -44,30 +75,52 @@ func (s *Server) SetDeployFunc(fn DeployFunc) {
 	s.deployFn = fn
 }
 
+func (s *Server) SetRollbackFunc(fn RollbackFunc) {
+	s.rollbackFn = fn
+}
+
 func (s *Server) routes() http.Handler {
 	mux := http.NewServeMux()
 
 	// Unauthenticated
 	mux.HandleFunc("GET /health", s.handleHealth)
 
-	// Authenticated routes
+	// Authenticated routes, each needing a token with the given scope
 	authed := http.NewServeMux()
-	authed.HandleFunc("GET /apps", s.handleListApps)
-	authed.HandleFunc("GET /apps/{app_id}/status", s.handleGetAppStatus)
-	authed.HandleFunc("POST /apps/deploy", s.handleDeploy)
-	authed.HandleFunc("DELETE /apps/{app_id}", s.handleTeardown)
-	authed.HandleFunc("POST /apps/{app_id}/restart", s.handleRestart)
-	authed.HandleFunc("POST /apps/{app_id}/exec", s.handleExec)
-	authed.HandleFunc("GET /apps/{app_id}/logs", s.handleLogs)
-	authed.HandleFunc("POST /update", s.handleUpdate)
+	authed.Handle("GET /apps", s.require(auth.ScopeRead, s.handleListApps))
+	authed.Handle("GET /apps/{app_id}/status", s.require(auth.ScopeRead, s.handleGetAppStatus))
+	authed.Handle("POST /apps/deploy", s.require(auth.ScopeDeploy, s.handleDeploy))
+	authed.Handle("DELETE /apps/{app_id}", s.require(auth.ScopeTeardown, s.handleTeardown))
+	authed.Handle("POST /apps/{app_id}/restart", s.require(auth.ScopeDeploy, s.handleRestart))
+	authed.Handle("POST /apps/{app_id}/rollback", s.require(auth.ScopeDeploy, s.handleRollback))
+	authed.Handle("POST /apps/{app_id}/sleep", s.require(auth.ScopeDeploy, s.handleSleep))
+	authed.Handle("POST /apps/{app_id}/wake", s.require(auth.ScopeDeploy, s.handleWake))
+	authed.Handle("GET /apps/{app_id}/processes", s.require(auth.ScopeRead, s.handleListProcesses))
+	authed.Handle("POST /apps/{app_id}/processes/{name}/start", s.require(auth.ScopeDeploy, s.handleProcessStart))
+	authed.Handle("POST /apps/{app_id}/processes/{name}/stop", s.require(auth.ScopeDeploy, s.handleProcessStop))
+	authed.Handle("POST /apps/{app_id}/processes/{name}/restart", s.require(auth.ScopeDeploy, s.handleProcessRestart))
+	authed.Handle("POST /apps/{app_id}/scale", s.require(auth.ScopeDeploy, s.handleScale))
+	authed.Handle("POST /apps/{app_id}/exec", s.require(auth.ScopeExec, s.handleExec))
+	authed.Handle("GET /apps/{app_id}/exec", s.require(auth.ScopeExec, s.handleListExecJobs))
+	authed.Handle("GET /apps/{app_id}/exec/{job_id}", s.require(auth.ScopeExec, s.handleGetExecJob))
+	authed.Handle("GET /apps/{app_id}/exec/{job_id}/events", s.require(auth.ScopeExec, s.handleExecJobEvents))
+	authed.Handle("DELETE /apps/{app_id}/exec/{job_id}", s.require(auth.ScopeExec, s.handleKillExecJob))
+	authed.Handle("GET /apps/{app_id}/console", s.require(auth.ScopeExec, s.handleConsole))
+	authed.Handle("GET /apps/{app_id}/logs", s.require(auth.ScopeRead, s.handleLogs))
+	authed.Handle("GET /apps/{app_id}/disk", s.require(auth.ScopeRead, s.handleAppDisk))
+	authed.Handle("GET /disk", s.require(auth.ScopeRead, s.handleDisk))
+	authed.Handle("POST /update", s.require(auth.ScopeUpdate, s.handleUpdate))
 
 	// WebSocket log streaming — uses streamAuthMiddleware (accepts stream token via query param).
 	// Registered with method+path which is more specific than the "/apps/" subtree pattern below.
 	mux.Handle("GET /apps/{app_id}/logs/stream", s.streamAuthMiddleware(http.HandlerFunc(s.handleLogStream)))
+	mux.Handle("GET /apps/{app_id}/logs/events", s.streamAuthMiddleware(http.HandlerFunc(s.handleLogEvents)))
 
-	mux.Handle("/apps/", s.authMiddleware(authed))
-	mux.Handle("/apps", s.authMiddleware(authed))
-	mux.Handle("/update", s.authMiddleware(authed))
+	protected := s.clientCertMiddleware(s.authMiddleware(s.signatureMiddleware(authed)))
+	mux.Handle("/apps/", protected)
+	mux.Handle("/apps", protected)
+	mux.Handle("/update", protected)
+	mux.Handle("/disk", protected)
 
 	var handler http.Handler = mux
 	handler = loggingMiddleware(handler)
@@ -85,6 +138,12 @@ func (s *Server) Start() error {
 		IdleTimeout:  120 * time.Second,
 	}
 
+	if s.certs != nil {
+		s.httpSrv.TLSConfig = s.certs.TLSConfig()
+		log.Printf("rad listening on %s (dev=%v, tls=true, mtls=%v)", s.cfg.Server.Listen, s.cfg.Dev, s.certs.RequiresClientCerts())
+		return s.httpSrv.ListenAndServeTLS("", "")
+	}
+
 	log.Printf("rad listening on %s (dev=%v)", s.cfg.Server.Listen, s.cfg.Dev)
 	return s.httpSrv.ListenAndServe()
 }
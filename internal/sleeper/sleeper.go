@@ -0,0 +1,313 @@
+package sleeper
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/caddy"
+	"github.com/reviewapps-dev/rad/internal/callback"
+	"github.com/reviewapps-dev/rad/internal/config"
+	"github.com/reviewapps-dev/rad/internal/health"
+	"github.com/reviewapps-dev/rad/internal/logstream"
+	"github.com/reviewapps-dev/rad/internal/supervisor"
+)
+
+// Sleeper stops the processes of apps that haven't served a request for a
+// while and starts them again on the next one.
+//
+// Idle time is measured from the mtime of the app's Caddy access log. A
+// sleeping app's Caddy site points at rad's wake listener, which holds the
+// request, starts the app, waits for its health check, points Caddy back at
+// the app and forwards the request.
+type Sleeper struct {
+	cfg      *config.Config
+	store    *app.Store
+	sup      *supervisor.Supervisor
+	caddy    *caddy.Manager
+	interval time.Duration
+	done     chan struct{}
+	wakeSrv  *http.Server
+
+	mu     sync.Mutex
+	waking map[string]*wakeCall
+}
+
+// ErrUnavailable is returned by Sleep when nothing would wake the app again:
+// sleeping is disabled or Caddy isn't managed, so the wake listener isn't
+// running.
+var ErrUnavailable = errors.New("sleep is unavailable: the wake listener isn't running (needs [sleep] enabled and caddy)")
+
+// wakeCall lets concurrent requests for the same app share one wake-up.
+type wakeCall struct {
+	done chan struct{}
+	err  error
+}
+
+func New(cfg *config.Config, store *app.Store, sup *supervisor.Supervisor, cm *caddy.Manager, interval time.Duration) *Sleeper {
+	return &Sleeper{
+		cfg:      cfg,
+		store:    store,
+		sup:      sup,
+		caddy:    cm,
+		interval: interval,
+		done:     make(chan struct{}),
+		waking:   make(map[string]*wakeCall),
+	}
+}
+
+func (s *Sleeper) Start() {
+	if !s.cfg.Sleep.Enabled {
+		log.Printf("sleeper: disabled")
+		return
+	}
+	if !s.caddy.Enabled {
+		log.Printf("sleeper: disabled (needs caddy for idle tracking and wake-on-request)")
+		return
+	}
+
+	s.wakeSrv = &http.Server{
+		Addr:    s.cfg.Sleep.WakeListen,
+		Handler: s,
+	}
+	go func() {
+		if err := s.wakeSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("sleeper: wake listener: %v", err)
+		}
+	}()
+
+	go s.loop()
+	log.Printf("sleeper: started (idle=%dm, wake listener=%s)", s.cfg.Sleep.IdleMinutes, s.cfg.Sleep.WakeListen)
+}
+
+func (s *Sleeper) Stop() {
+	if s.wakeSrv == nil {
+		return
+	}
+	close(s.done)
+	s.wakeSrv.Close()
+	log.Printf("sleeper: stopped")
+}
+
+func (s *Sleeper) loop() {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.check()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Sleeper) check() {
+	idle := time.Duration(s.cfg.Sleep.IdleMinutes) * time.Minute
+	for _, state := range s.store.List() {
+		if state.Status != app.StatusRunning {
+			continue
+		}
+		last := s.LastActivity(state)
+		if time.Since(last) < idle {
+			continue
+		}
+
+		log.Printf("sleeper: %s idle since %s, putting to sleep", state.AppID, last.Format(time.RFC3339))
+		if err := s.Sleep(state.AppID); err != nil {
+			log.Printf("sleeper: sleep %s: %v", state.AppID, err)
+		}
+	}
+}
+
+// LastActivity returns when the app last served a request, or when its web
+// process started if that's more recent.
+func (s *Sleeper) LastActivity(state *app.AppState) time.Time {
+	last := state.Processes["web"].StartedAt
+	if last.IsZero() {
+		last = state.UpdatedAt
+	}
+	accessLog := filepath.Join(s.cfg.Paths.LogDir, state.AppID+".access.log")
+	if info, err := os.Stat(accessLog); err == nil && info.ModTime().After(last) {
+		last = info.ModTime()
+	}
+	return last
+}
+
+// Sleep points the app's Caddy site at the wake listener and stops its
+// processes. The port stays reserved for when the app wakes.
+func (s *Sleeper) Sleep(appID string) error {
+	if s.wakeSrv == nil {
+		return ErrUnavailable
+	}
+
+	state, err := s.store.Get(appID)
+	if err != nil {
+		return err
+	}
+	if state.Status != app.StatusRunning {
+		return fmt.Errorf("app is not running (status: %s)", state.Status)
+	}
+
+	// Switch traffic first: if Caddy can't be reloaded the app keeps running
+	site := s.siteConfig(state)
+	site.WakeAddr = s.cfg.Sleep.WakeListen
+	if err := s.caddy.WriteSiteConfig(site); err != nil {
+		return err
+	}
+	if err := s.caddy.Reload(); err != nil {
+		_ = s.caddy.WriteSiteConfig(s.siteConfig(state))
+		return err
+	}
+
+	// Mark sleeping before stopping so the crash monitor leaves the processes alone
+	_ = s.store.UpdateStatus(appID, app.StatusSleeping, "")
+	s.sup.StopAll(state)
+	_ = s.store.ClearProcesses(appID)
+
+	log.Printf("sleeper: %s is sleeping", appID)
+	s.sendStatus(state, app.StatusSleeping)
+	return nil
+}
+
+// Wake starts a sleeping app and points Caddy back at it. Concurrent calls
+// for the same app wait for a single wake-up. Returns nil if the app is
+// already running.
+func (s *Sleeper) Wake(appID string) error {
+	s.mu.Lock()
+	if c, ok := s.waking[appID]; ok {
+		s.mu.Unlock()
+		<-c.done
+		return c.err
+	}
+	c := &wakeCall{done: make(chan struct{})}
+	s.waking[appID] = c
+	s.mu.Unlock()
+
+	c.err = s.wake(appID)
+
+	s.mu.Lock()
+	delete(s.waking, appID)
+	s.mu.Unlock()
+	close(c.done)
+	return c.err
+}
+
+func (s *Sleeper) wake(appID string) error {
+	state, err := s.store.Get(appID)
+	if err != nil {
+		return err
+	}
+	switch state.Status {
+	case app.StatusRunning:
+		return nil
+	case app.StatusSleeping:
+	default:
+		return fmt.Errorf("app is not sleeping (status: %s)", state.Status)
+	}
+
+	log.Printf("sleeper: waking %s", appID)
+	start := time.Now()
+	_ = s.store.UpdateStatus(appID, app.StatusStarting, "")
+
+	fail := func(err error) error {
+		s.sup.StopAll(state)
+		_ = s.store.ClearProcesses(appID)
+		_ = s.store.UpdateStatus(appID, app.StatusSleeping, "wake failed: "+err.Error())
+		log.Printf("sleeper: wake %s failed: %v", appID, err)
+		return err
+	}
+
+	if err := s.sup.StartAll(state); err != nil {
+		return fail(err)
+	}
+
+	// In dev mode, don't set Host header — just use localhost
+	host := ""
+	if !s.cfg.Dev {
+		host = state.Subdomain
+		if host == "" {
+			host = state.AppID
+		}
+	}
+	timeout := time.Duration(s.cfg.Sleep.WakeTimeout) * time.Second
+	if err := health.Check(state.Port, host, timeout, 500*time.Millisecond, ""); err != nil {
+		s.sup.Event(appID, logstream.EventHealth, "web", "health check failed on wake: %v", err)
+		return fail(err)
+	}
+
+	// Non-fatal: until Caddy reloads, requests keep coming through the wake
+	// listener, which forwards them to the running app
+	if err := s.caddy.WriteSiteConfig(s.siteConfig(state)); err != nil {
+		log.Printf("sleeper: restore caddy config for %s: %v", appID, err)
+	} else if err := s.caddy.Reload(); err != nil {
+		log.Printf("sleeper: caddy reload for %s: %v", appID, err)
+	}
+
+	_ = s.store.UpdateStatus(appID, app.StatusRunning, "")
+	log.Printf("sleeper: %s is awake (%s)", appID, time.Since(start).Round(time.Millisecond))
+	s.sendStatus(state, app.StatusRunning)
+	return nil
+}
+
+// ServeHTTP handles requests Caddy forwards for sleeping apps: the request is
+// held until the app is up, then proxied to it.
+func (s *Sleeper) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	appID := r.Header.Get(caddy.WakeHeader)
+	if appID == "" {
+		http.Error(w, "missing "+caddy.WakeHeader+" header", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.Wake(appID); err != nil {
+		http.Error(w, "review app failed to start: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	state, err := s.store.Get(appID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	r.Header.Del(caddy.WakeHeader)
+	target := &url.URL{Scheme: "http", Host: fmt.Sprintf("localhost:%d", state.Port)}
+	httputil.NewSingleHostReverseProxy(target).ServeHTTP(w, r)
+}
+
+func (s *Sleeper) siteConfig(state *app.AppState) caddy.SiteConfig {
+	subdomain := state.Subdomain
+	if subdomain == "" {
+		subdomain = state.AppID
+	}
+	return caddy.SiteConfig{
+		AppID:     state.AppID,
+		Subdomain: subdomain,
+		Port:      state.Port,
+		LogDir:    s.cfg.Paths.LogDir,
+	}
+}
+
+// sendStatus notifies the web app in the background, so a held request
+// isn't kept waiting on callback retries.
+func (s *Sleeper) sendStatus(state *app.AppState, status app.Status) {
+	if state.CallbackURL == "" {
+		return
+	}
+	payload := callback.StatusPayload{
+		AppID:     state.AppID,
+		Status:    string(status),
+		CommitSHA: state.CommitSHA,
+		Port:      state.Port,
+	}
+	go callback.NewClient(s.cfg.API.APIKey, s.cfg.Auth.RequestSigningKey).SendStatus(state.CallbackURL, payload)
+}
@@ -0,0 +1,327 @@
+package supervisor
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/config"
+	"github.com/reviewapps-dev/rad/internal/logstream"
+	"github.com/reviewapps-dev/rad/internal/logwriter"
+	"github.com/reviewapps-dev/rad/internal/process"
+	"github.com/reviewapps-dev/rad/internal/rv"
+)
+
+// Supervisor starts and stops the processes of a deployed app outside of the
+// deploy pipeline, using the commands saved in AppState.ProcessCommands.
+// It's shared by the API handlers and the crash monitor.
+type Supervisor struct {
+	cfg    *config.Config
+	store  *app.Store
+	events *logstream.Events
+}
+
+func New(cfg *config.Config, store *app.Store, events *logstream.Events) *Supervisor {
+	return &Supervisor{
+		cfg:    cfg,
+		store:  store,
+		events: events,
+	}
+}
+
+// Event publishes a lifecycle event for one of the app's processes.
+func (s *Supervisor) Event(appID, typ, name, format string, args ...any) {
+	s.events.Publish(logstream.Event{
+		AppID:   appID,
+		Type:    typ,
+		Process: name,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// LogPath returns the log file path for a process.
+// web → {app_id}.log, others → {app_id}.{name}.log (worker.2 → {app_id}.worker.2.log)
+// A name that could leave the log dir (containing / or ..) gets an empty path.
+func (s *Supervisor) LogPath(appID, name string) string {
+	if strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return ""
+	}
+	if name == "web" {
+		return filepath.Join(s.cfg.Paths.LogDir, appID+".log")
+	}
+	return filepath.Join(s.cfg.Paths.LogDir, appID+"."+name+".log")
+}
+
+// ProcessLogs returns the log file of every process that has one, by process
+// name. The Caddy access log is not a process log and is left out.
+func (s *Supervisor) ProcessLogs(appID string) map[string]string {
+	logs := make(map[string]string)
+	if _, err := os.Stat(s.LogPath(appID, "web")); err == nil {
+		logs["web"] = s.LogPath(appID, "web")
+	}
+	matches, _ := filepath.Glob(filepath.Join(s.cfg.Paths.LogDir, appID+".*.log"))
+	for _, m := range matches {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(m), appID+"."), ".log")
+		if name == "access" {
+			continue
+		}
+		logs[name] = m
+	}
+	return logs
+}
+
+// LoadEnv reads the .env file for an app and returns the env vars as a slice.
+// Does NOT include os.Environ() — rv.ExecInDir/RunInDir already prepends that.
+func (s *Supervisor) LoadEnv(state *app.AppState) []string {
+	var envSlice []string
+	envPath := filepath.Join(state.AppDir, ".env")
+	data, err := os.ReadFile(envPath)
+	if err != nil {
+		return envSlice
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		envSlice = append(envSlice, line)
+	}
+	return envSlice
+}
+
+// Commands returns the saved process commands for an app, falling back to a
+// plain rails server when none were recorded (apps deployed before
+// multi-process support).
+func (s *Supervisor) Commands(state *app.AppState) map[string]string {
+	if len(state.ProcessCommands) > 0 {
+		return state.ProcessCommands
+	}
+	return map[string]string{
+		"web": fmt.Sprintf("bin/rails server -p %d -e production", state.Port),
+	}
+}
+
+// Instances returns the process instances an app should be running, keyed by
+// instance name (web, worker.1, worker.2, ...), with their commands.
+func (s *Supervisor) Instances(state *app.AppState) map[string]string {
+	instances := make(map[string]string)
+	for procType, cmd := range s.Commands(state) {
+		for _, name := range app.InstanceNames(procType, state.ScaleFor(procType)) {
+			instances[name] = cmd
+		}
+	}
+	return instances
+}
+
+// HasProcess reports whether name is one of the app's instances, declared or
+// recorded. Names from requests are checked with it before their log is read.
+func (s *Supervisor) HasProcess(state *app.AppState, name string) bool {
+	if _, ok := s.Instances(state)[name]; ok {
+		return true
+	}
+	_, ok := state.Processes[name]
+	return ok
+}
+
+// Scale starts or stops instances of a process type until count are running,
+// and records the count as a runtime override.
+func (s *Supervisor) Scale(state *app.AppState, procType string, count int) error {
+	if _, ok := s.Commands(state)[procType]; !ok {
+		return fmt.Errorf("no saved command for process %q", procType)
+	}
+	if err := app.ValidateScale(procType, count); err != nil {
+		return err
+	}
+
+	if err := s.store.SetScaleOverride(state.AppID, procType, count); err != nil {
+		return err
+	}
+
+	want := make(map[string]bool, count)
+	for _, name := range app.InstanceNames(procType, count) {
+		want[name] = true
+	}
+
+	// Stop instances that are no longer wanted (including the unnumbered
+	// name when going from one instance to several, and vice versa)
+	for name, proc := range state.Processes {
+		if app.ProcessType(name) != procType || want[name] {
+			continue
+		}
+		if proc.PID > 0 {
+			log.Printf("supervisor: scaling down %s/%s (pid=%d)", state.AppID, name, proc.PID)
+			process.Stop(proc.PID)
+		}
+		_ = s.store.RemoveProcess(state.AppID, name)
+	}
+
+	for _, name := range app.InstanceNames(procType, count) {
+		if process.IsAlive(state.Processes[name].PID) {
+			continue
+		}
+		if _, err := s.Start(state, name); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("supervisor: scaled %s/%s to %d", state.AppID, procType, count)
+	return nil
+}
+
+// Start starts a single process from its saved command and records it in the store.
+func (s *Supervisor) Start(state *app.AppState, name string) (app.ProcessInfo, error) {
+	return s.start(state, name, state.Processes[name].Restarts)
+}
+
+// Stop stops a single process. The process stays in AppState.Processes with
+// PID 0 so it's reported as stopped and the monitor doesn't revive it.
+func (s *Supervisor) Stop(state *app.AppState, name string) error {
+	proc, ok := state.Processes[name]
+	if !ok {
+		return fmt.Errorf("process %q is not running", name)
+	}
+	if proc.PID > 0 {
+		log.Printf("supervisor: stopping %s/%s (pid=%d)", state.AppID, name, proc.PID)
+		if err := process.Stop(proc.PID); err != nil {
+			return fmt.Errorf("stop %s: %w", name, err)
+		}
+	}
+	proc.PID = 0
+	proc.StartedAt = time.Time{}
+	return s.store.SetProcess(state.AppID, proc)
+}
+
+// Restart stops a single process (if running) and starts it again.
+func (s *Supervisor) Restart(state *app.AppState, name string) (app.ProcessInfo, error) {
+	prev := state.Processes[name]
+	if prev.PID > 0 {
+		log.Printf("supervisor: stopping %s/%s (pid=%d)", state.AppID, name, prev.PID)
+		process.Stop(prev.PID)
+	}
+	proc, err := s.start(state, name, prev.Restarts+1)
+	if err == nil {
+		s.Event(state.AppID, logstream.EventRestart, name, "restarted (pid=%d)", proc.PID)
+	}
+	return proc, err
+}
+
+// Respawn starts a process that has died on its own. Counted as a restart.
+func (s *Supervisor) Respawn(state *app.AppState, name string) (app.ProcessInfo, error) {
+	prev := state.Processes[name]
+	s.Event(state.AppID, logstream.EventCrash, name, "exited (pid=%d)", prev.PID)
+	proc, err := s.start(state, name, prev.Restarts+1)
+	if err != nil {
+		s.Event(state.AppID, logstream.EventRestart, name, "restart failed: %v", err)
+		return proc, err
+	}
+	s.Event(state.AppID, logstream.EventRestart, name, "restarted after crash (pid=%d, restarts=%d)", proc.PID, proc.Restarts)
+	return proc, nil
+}
+
+// StopAll stops every tracked process for the app.
+func (s *Supervisor) StopAll(state *app.AppState) {
+	if len(state.Processes) > 0 {
+		for name, proc := range state.Processes {
+			if proc.PID > 0 {
+				log.Printf("supervisor: stopping %s/%s (pid=%d)", state.AppID, name, proc.PID)
+				if err := process.Stop(proc.PID); err != nil {
+					log.Printf("supervisor: stop %s/%s: %v", state.AppID, name, err)
+				}
+			}
+		}
+	} else if state.PID > 0 {
+		// Backward compat: single PID from before multi-process
+		log.Printf("supervisor: stopping %s (pid=%d)", state.AppID, state.PID)
+		if err := process.Stop(state.PID); err != nil {
+			log.Printf("supervisor: stop %s: %v", state.AppID, err)
+		}
+	}
+}
+
+// StartAll starts every declared instance that isn't already running, web first.
+func (s *Supervisor) StartAll(state *app.AppState) error {
+	procs := s.Instances(state)
+	for _, name := range SortNames(procs) {
+		if process.IsAlive(state.Processes[name].PID) {
+			continue
+		}
+		if _, err := s.Start(state, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestartAll stops every process and starts all saved commands again, web first.
+func (s *Supervisor) RestartAll(state *app.AppState) error {
+	restarts := make(map[string]int, len(state.Processes))
+	for name, proc := range state.Processes {
+		restarts[name] = proc.Restarts
+	}
+
+	s.StopAll(state)
+	_ = s.store.ClearProcesses(state.AppID)
+
+	procs := s.Instances(state)
+	for _, name := range SortNames(procs) {
+		if _, err := s.start(state, name, restarts[name]+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Supervisor) start(state *app.AppState, name string, restarts int) (app.ProcessInfo, error) {
+	cmd, ok := s.Commands(state)[app.ProcessType(name)]
+	if !ok {
+		return app.ProcessInfo{}, fmt.Errorf("no saved command for process %q", name)
+	}
+
+	// Expand $PORT for web process
+	if name == "web" {
+		cmd = strings.ReplaceAll(cmd, "$PORT", fmt.Sprintf("%d", state.Port))
+	}
+
+	log.Printf("supervisor: starting %s/%s: %s", state.AppID, name, cmd)
+
+	execCmd := rv.ExecInDir(state.RepoDir(), state.RubyVersion, s.LoadEnv(state), cmd)
+	info, err := logwriter.Start(execCmd, s.LogPath(state.AppID, name), name, logwriter.OptionsFromConfig(s.cfg.Logs))
+	if err != nil {
+		return app.ProcessInfo{}, fmt.Errorf("start process %s: %w", name, err)
+	}
+
+	proc := app.ProcessInfo{
+		Name:      name,
+		PID:       info.PID,
+		StartedAt: time.Now(),
+		Restarts:  restarts,
+	}
+	if name == "web" {
+		proc.Port = state.Port
+	}
+	_ = s.store.SetProcess(state.AppID, proc)
+
+	log.Printf("supervisor: %s/%s started (pid=%d)", state.AppID, name, info.PID)
+	return proc, nil
+}
+
+// SortNames returns process names with "web" first, then the rest alphabetically.
+func SortNames(procs map[string]string) []string {
+	names := make([]string, 0, len(procs))
+	for name := range procs {
+		if name != "web" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	// web always goes first
+	if _, ok := procs["web"]; ok {
+		names = append([]string{"web"}, names...)
+	}
+	return names
+}
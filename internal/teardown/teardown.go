@@ -0,0 +1,292 @@
+package teardown
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/reviewapps-dev/rad/internal/app"
+	"github.com/reviewapps-dev/rad/internal/buildqueue"
+	"github.com/reviewapps-dev/rad/internal/caddy"
+	"github.com/reviewapps-dev/rad/internal/callback"
+	"github.com/reviewapps-dev/rad/internal/config"
+	"github.com/reviewapps-dev/rad/internal/database"
+	"github.com/reviewapps-dev/rad/internal/deploy"
+	"github.com/reviewapps-dev/rad/internal/diskusage"
+	"github.com/reviewapps-dev/rad/internal/execjob"
+	"github.com/reviewapps-dev/rad/internal/logging"
+	"github.com/reviewapps-dev/rad/internal/logstream"
+	"github.com/reviewapps-dev/rad/internal/port"
+	"github.com/reviewapps-dev/rad/internal/process"
+	"github.com/reviewapps-dev/rad/internal/reviewappsyml"
+	"github.com/reviewapps-dev/rad/internal/supervisor"
+)
+
+// Callback statuses sent to the web app once an app is gone.
+const (
+	StatusRemoved = "removed" // torn down via DELETE /apps/{id}
+	StatusExpired = "expired" // torn down by the reaper after its TTL ran out
+	StatusEvicted = "evicted" // torn down to make room for another deploy
+)
+
+// stepAttempts is how many times a failing step is tried before the teardown
+// is given up and marked failed.
+const stepAttempts = 3
+
+// Teardown removes a review app and everything it owns on the server. It's
+// shared by the API and the expiry reaper. Teardowns run as build queue jobs
+// so a slow one (stopping processes, dropping databases) never holds up an
+// HTTP request.
+type Teardown struct {
+	cfg   *config.Config
+	store *app.Store
+	ports *port.Allocator
+	caddy *caddy.Manager
+	sup   *supervisor.Supervisor
+	queue *buildqueue.Queue
+	hub   *logstream.Hub
+	jobs  *execjob.Manager
+}
+
+func New(cfg *config.Config, store *app.Store, ports *port.Allocator, cm *caddy.Manager, sup *supervisor.Supervisor, queue *buildqueue.Queue, hub *logstream.Hub, jobs *execjob.Manager) *Teardown {
+	return &Teardown{
+		cfg:   cfg,
+		store: store,
+		ports: ports,
+		caddy: cm,
+		sup:   sup,
+		queue: queue,
+		hub:   hub,
+		jobs:  jobs,
+	}
+}
+
+type step struct {
+	name string
+	fn   func(state *app.AppState, logger *logging.DeployLogger) error
+}
+
+func (t *Teardown) steps() []step {
+	return []step{
+		{"before-teardown-hooks", t.runHooks},
+		{"stop-processes", t.stopProcesses},
+		{"release-port", t.releasePort},
+		{"drop-databases", t.dropDatabases},
+		{"remove-caddy-site", t.removeCaddySite},
+		{"remove-logs", t.removeLogs},
+		{"remove-app-dir", t.removeAppDir},
+	}
+}
+
+// Enqueue marks the app as tearing down and queues the teardown. status is
+// reported to the callback URL once the app is gone.
+func (t *Teardown) Enqueue(state *app.AppState, status string) error {
+	return t.EnqueueBefore(state, status)
+}
+
+// EnqueueBefore is Enqueue with more jobs queued right after the teardown,
+// all or none: an eviction is only queued along with the deploy it makes
+// room for.
+func (t *Teardown) EnqueueBefore(state *app.AppState, status string, then ...buildqueue.Job) error {
+	appID := state.AppID
+	jobs := append([]buildqueue.Job{{
+		AppID: appID,
+		Fn: func(ctx context.Context) error {
+			return t.Run(ctx, state, status)
+		},
+	}}, then...)
+	if !t.queue.Enqueue(jobs...) {
+		return fmt.Errorf("build queue full")
+	}
+	_ = t.store.UpdateStatus(appID, app.StatusTeardown, "")
+	return nil
+}
+
+// Run tears the app down step by step and deletes it from the store. Steps
+// that completed in an earlier, failed teardown are skipped, and each step is
+// retried a few times before giving up. On failure the app is left in the
+// store as failed, so DELETE can be retried.
+func (t *Teardown) Run(ctx context.Context, state *app.AppState, status string) error {
+	appID := state.AppID
+	_ = t.store.UpdateStatus(appID, app.StatusTeardown, "")
+	deleted := false
+	defer func() {
+		t.hub.Close(appID)
+		// The app is gone: let log streams read the teardown's last lines,
+		// then drop its buffer
+		if deleted {
+			t.hub.Remove(appID)
+		}
+	}()
+
+	buildLog := deploy.StartBuildLog(t.cfg, t.store, state, app.BuildTeardown)
+	defer buildLog.Close()
+
+	logger := logging.NewDeployLogger(appID, func(appID, line string) {
+		buildLog.WriteLine(line)
+		t.hub.Publish(appID, line)
+	})
+	logger.Log("starting teardown for %s", appID)
+
+	done := make(map[string]bool, len(state.TeardownDone))
+	for _, name := range state.TeardownDone {
+		done[name] = true
+	}
+
+	for _, s := range t.steps() {
+		if done[s.name] {
+			logger.Log("step: %s (already done)", s.name)
+			continue
+		}
+
+		logger.Log("step: %s", s.name)
+		err := t.runStep(ctx, s, state, logger)
+		if err != nil {
+			err = fmt.Errorf("teardown step %s: %w", s.name, err)
+			_ = t.store.UpdateStatus(appID, app.StatusFailed, err.Error())
+			_ = t.store.RecordTeardownFailure(appID)
+			return err
+		}
+		_ = t.store.MarkTeardownStep(appID, s.name)
+	}
+
+	if err := t.store.Delete(appID); err != nil {
+		return err
+	}
+	deleted = true
+	logger.Log("teardown complete for %s", appID)
+
+	// Send teardown callback to web app
+	if state.CallbackURL != "" {
+		client := callback.NewClient(t.cfg.API.APIKey, t.cfg.Auth.RequestSigningKey)
+		client.SendStatus(state.CallbackURL, callback.StatusPayload{
+			AppID:  appID,
+			Status: status,
+		})
+	}
+
+	return nil
+}
+
+// runStep runs a step, retrying with a growing delay if it fails.
+func (t *Teardown) runStep(ctx context.Context, s step, state *app.AppState, logger *logging.DeployLogger) error {
+	var err error
+	for attempt := 1; attempt <= stepAttempts; attempt++ {
+		if err = s.fn(state, logger); err == nil {
+			return nil
+		}
+		logger.Log("step %s failed (attempt %d/%d): %v", s.name, attempt, stepAttempts, err)
+		if attempt == stepAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("teardown cancelled")
+		case <-time.After(time.Duration(attempt) * 2 * time.Second):
+		}
+	}
+	return err
+}
+
+// runHooks runs before_teardown hooks. Best-effort: hook errors don't stop
+// the teardown.
+func (t *Teardown) runHooks(state *app.AppState, logger *logging.DeployLogger) error {
+	if state.AppDir == "" {
+		return nil
+	}
+	ymlPath := filepath.Join(state.ReleaseDir(), "reviewapps.yml")
+	if _, err := os.Stat(ymlPath); err != nil {
+		return nil
+	}
+	cfg, err := reviewappsyml.Parse(ymlPath)
+	if err != nil {
+		logger.Log("parse reviewapps.yml: %v (skipping hooks)", err)
+		return nil
+	}
+	out := logger.Writer(string(deploy.HookBeforeTeardown))
+	defer out.Close()
+	if err := deploy.RunHooksFromConfig(cfg, deploy.HookBeforeTeardown, state.RepoDir(), state.RubyVersion, t.sup.LoadEnv(state), out); err != nil {
+		logger.Log("before_teardown hook error (non-fatal): %v", err)
+	}
+	return nil
+}
+
+func (t *Teardown) stopProcesses(state *app.AppState, logger *logging.DeployLogger) error {
+	logger.Log("stopping %d process(es)", len(state.Processes))
+	t.sup.StopAll(state)
+	t.jobs.KillApp(state.AppID)
+
+	for name, proc := range state.Processes {
+		if process.IsAlive(proc.PID) {
+			return fmt.Errorf("process %s (pid=%d) is still running", name, proc.PID)
+		}
+	}
+	if len(state.Processes) == 0 && process.IsAlive(state.PID) {
+		return fmt.Errorf("process %d is still running", state.PID)
+	}
+
+	// Forget the PIDs so a retry can't signal a recycled one
+	return t.store.ClearProcesses(state.AppID)
+}
+
+func (t *Teardown) releasePort(state *app.AppState, logger *logging.DeployLogger) error {
+	t.ports.Release(state.AppID)
+	return nil
+}
+
+// dropDatabases drops the app's PostgreSQL databases. SQLite databases live
+// in the app directory and go with it.
+func (t *Teardown) dropDatabases(state *app.AppState, logger *logging.DeployLogger) error {
+	databases := state.Databases
+	if len(databases) == 0 && (state.DatabaseAdapter == "postgresql" || state.DatabaseAdapter == "postgres") {
+		databases = map[string]string{"primary": state.DatabaseAdapter}
+	}
+	for name, adapter := range databases {
+		if adapter == "postgresql" || adapter == "postgres" {
+			dbCfg := &database.DBConfig{AppID: state.AppID, Name: name, Adapter: adapter}
+			dbName := dbCfg.DBName()
+			logger.Log("dropping database %s", dbName)
+			if err := database.DropPostgresDB(dbName); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (t *Teardown) removeCaddySite(state *app.AppState, logger *logging.DeployLogger) error {
+	if t.caddy == nil || !t.caddy.Enabled {
+		return nil
+	}
+	if err := t.caddy.RemoveSiteConfig(state.AppID); err != nil {
+		return err
+	}
+	// Non-fatal: the site is gone from disk and drops out on Caddy's next reload
+	if err := t.caddy.Reload(); err != nil {
+		logger.Log("caddy reload failed (non-fatal): %v", err)
+	}
+	return nil
+}
+
+// removeLogs removes the app's process and access logs, their rotated
+// segments and its build logs.
+func (t *Teardown) removeLogs(state *app.AppState, logger *logging.DeployLogger) error {
+	for _, f := range diskusage.LogFiles(t.cfg, state.AppID) {
+		logger.Log("removing log %s", f)
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	// This teardown's own log goes too; lines written after this are lost
+	return os.RemoveAll(filepath.Join(t.cfg.Paths.LogDir, state.AppID))
+}
+
+func (t *Teardown) removeAppDir(state *app.AppState, logger *logging.DeployLogger) error {
+	if state.AppDir == "" {
+		return nil
+	}
+	logger.Log("removing %s", state.AppDir)
+	return os.RemoveAll(state.AppDir)
+}